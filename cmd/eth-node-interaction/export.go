@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"github.com/avalkov/eth-node-interaction/internal/chains"
+	"github.com/avalkov/eth-node-interaction/internal/config"
+	"github.com/avalkov/eth-node-interaction/internal/exporter"
+	dbstorage "github.com/avalkov/eth-node-interaction/internal/storage/db"
+	"github.com/xo/dburl"
+)
+
+// runExport writes cached transactions, their logs or token transfers to a file or stdout:
+// eth-node-interaction export -format ndjson -dataset transfers -from-block 100 -out transfers.ndjson
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", string(exporter.CsvFormat), "csv or ndjson")
+	dataset := flags.String("dataset", string(exporter.TransactionsDataset), "transactions, logs or transfers")
+	columns := flags.String("columns", "", "comma separated columns, all when empty")
+	chain := flags.String("chain", "", "chain name or ID, the default chain when empty")
+	fromBlock := flags.String("from-block", "", "first block number")
+	toBlock := flags.String("to-block", "", "last block number")
+	address := flags.String("address", "", "sender, recipient or created contract")
+	status := flags.String("status", "", "transaction status name or number")
+	out := flags.String("out", "", "output file, stdout when empty")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	request, err := exporter.RequestFromValues(url.Values{
+		"format":    {*format},
+		"dataset":   {*dataset},
+		"columns":   {*columns},
+		"chain":     {*chain},
+		"fromBlock": {*fromBlock},
+		"toBlock":   {*toBlock},
+		"address":   {*address},
+		"status":    {*status},
+	})
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.NewConfig(".env")
+	if err != nil {
+		return fmt.Errorf("creating config failed: %s", err)
+	}
+
+	chainRegistry, err := chains.NewRegistry(context.Background(), cfg.Chains, cfg.DefaultChain)
+	if err != nil {
+		return err
+	}
+
+	parsedConnectionUrl, err := dburl.Parse(cfg.DbConnectionUrl)
+	if err != nil {
+		return err
+	}
+
+	storage, err := dbstorage.NewStorage(parsedConnectionUrl.Driver, parsedConnectionUrl.DSN)
+	if err != nil {
+		return err
+	}
+
+	if err := storage.ExecuteMigrations(context.Background()); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	return exporter.NewExporter(storage, chainRegistry).Export(context.Background(), w, request)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/avalkov/eth-node-interaction/internal/chains"
+	"github.com/avalkov/eth-node-interaction/internal/config"
+	"github.com/avalkov/eth-node-interaction/internal/importer"
+	dbstorage "github.com/avalkov/eth-node-interaction/internal/storage/db"
+	"github.com/xo/dburl"
+)
+
+// runImport seeds the cache with transactions from a file or stdin. Invalid rows are reported
+// on stderr with their line number:
+// eth-node-interaction import -format csv -sample-rate 0.01 -in transactions.csv
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", string(importer.NdjsonFormat), "csv or ndjson")
+	chain := flags.String("chain", "", "chain name or ID, the default chain when empty")
+	sampleRate := flags.Float64("sample-rate", 0, "share of mined transactions verified against the node, from 0 to 1")
+	in := flags.String("in", "", "input file, stdin when empty")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *sampleRate < 0 || *sampleRate > 1 {
+		return fmt.Errorf("invalid sample rate (%v)", *sampleRate)
+	}
+
+	cfg, err := config.NewConfig(".env")
+	if err != nil {
+		return fmt.Errorf("creating config failed: %s", err)
+	}
+
+	chainRegistry, err := chains.NewRegistry(context.Background(), cfg.Chains, cfg.DefaultChain)
+	if err != nil {
+		return err
+	}
+
+	parsedConnectionUrl, err := dburl.Parse(cfg.DbConnectionUrl)
+	if err != nil {
+		return err
+	}
+
+	storage, err := dbstorage.NewStorage(parsedConnectionUrl.Driver, parsedConnectionUrl.DSN)
+	if err != nil {
+		return err
+	}
+
+	if err := storage.ExecuteMigrations(context.Background()); err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "" {
+		file, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	report, err := importer.NewImporter(storage, chainRegistry, cfg.Confirmations).Import(context.Background(), r, importer.Request{
+		Format:     importer.Format(*format),
+		Chain:      *chain,
+		SampleRate: *sampleRate,
+	}, os.Stderr)
+
+	fmt.Fprintf(os.Stderr, "read %d lines: %d imported, %d already cached, %d invalid, %d verified against the node\n",
+		report.Lines, report.Imported, report.Cached, report.Invalid, report.Verified)
+
+	return err
+}
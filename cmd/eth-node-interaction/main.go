@@ -4,21 +4,55 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math/big"
 	"net/http"
+	"os"
 
+	accountstate "github.com/avalkov/eth-node-interaction/internal/account_state"
 	"github.com/avalkov/eth-node-interaction/internal/authenticator"
+	chainwatcher "github.com/avalkov/eth-node-interaction/internal/chain_watcher"
+	"github.com/avalkov/eth-node-interaction/internal/chains"
 	"github.com/avalkov/eth-node-interaction/internal/config"
+	contractinspector "github.com/avalkov/eth-node-interaction/internal/contract_inspector"
+	"github.com/avalkov/eth-node-interaction/internal/ens"
+	eventstream "github.com/avalkov/eth-node-interaction/internal/event_stream"
+	"github.com/avalkov/eth-node-interaction/internal/events"
+	"github.com/avalkov/eth-node-interaction/internal/exporter"
+	gasoracle "github.com/avalkov/eth-node-interaction/internal/gas_oracle"
+	graphqlapi "github.com/avalkov/eth-node-interaction/internal/graphql_api"
+	"github.com/avalkov/eth-node-interaction/internal/mempool"
+	noncemanager "github.com/avalkov/eth-node-interaction/internal/nonce_manager"
+	"github.com/avalkov/eth-node-interaction/internal/proofs"
+	restgateway "github.com/avalkov/eth-node-interaction/internal/rest_gateway"
 	rpccodecs "github.com/avalkov/eth-node-interaction/internal/rpc_codecs"
+	rpcdiscovery "github.com/avalkov/eth-node-interaction/internal/rpc_discovery"
 	rpcservices "github.com/avalkov/eth-node-interaction/internal/rpc_services"
+	rpcwebsocket "github.com/avalkov/eth-node-interaction/internal/rpc_websocket"
+	"github.com/avalkov/eth-node-interaction/internal/simulator"
 	dbstorage "github.com/avalkov/eth-node-interaction/internal/storage/db"
 	txfetcher "github.com/avalkov/eth-node-interaction/internal/tx_fetcher"
-	"github.com/ethereum/go-ethereum/ethclient"
+	txsender "github.com/avalkov/eth-node-interaction/internal/tx_sender"
+	"github.com/avalkov/eth-node-interaction/internal/verifier"
+	"github.com/avalkov/eth-node-interaction/internal/webhooks"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/gorilla/rpc"
 	"github.com/xo/dburl"
 )
 
 func main() {
-	if err := runService(); err != nil {
+	var err error
+	switch {
+	case len(os.Args) > 1 && os.Args[1] == "export":
+		err = runExport(os.Args[2:])
+	case len(os.Args) > 1 && os.Args[1] == "import":
+		err = runImport(os.Args[2:])
+	default:
+		err = runService()
+	}
+
+	if err != nil {
 		log.Fatal(err)
 	}
 }
@@ -30,7 +64,7 @@ func runService() error {
 		return fmt.Errorf("creating config failed: %s", err)
 	}
 
-	client, err := ethclient.Dial(cfg.EthNodeUrl)
+	chainRegistry, err := chains.NewRegistry(context.Background(), cfg.Chains, cfg.DefaultChain)
 	if err != nil {
 		return err
 	}
@@ -49,21 +83,110 @@ func runService() error {
 		return err
 	}
 
+	if err := storage.AssignLegacyTxs(context.Background(), chainRegistry.Default().ID); err != nil {
+		return err
+	}
+
 	server := rpc.NewServer()
 
 	codec := rpccodecs.NewCustomRequestsCodec()
 	server.RegisterCodec(codec, "application/json")
 	server.RegisterCodec(codec, "application/json;charset=UTF-8")
 
-	txFetcher := txfetcher.NewTxFetcher(storage, client)
+	managedAddresses := []common.Address{}
+	for _, address := range cfg.ManagedAddresses {
+		if !common.IsHexAddress(address) {
+			return fmt.Errorf("invalid managed address (%s)", address)
+		}
+		managedAddresses = append(managedAddresses, common.HexToAddress(address))
+	}
+
+	bus := events.NewBus()
+
+	dispatcher := webhooks.NewDispatcher(storage, webhooks.NewHttpClient(cfg.WebhookTimeout))
+	webhookEvents, _ := bus.SubscribeBlocking()
+	go dispatcher.Enqueue(context.Background(), webhookEvents)
+	go dispatcher.Deliver(context.Background(), cfg.WebhookPollTime)
+
+	journalEvents, _ := bus.SubscribeBlocking()
+	go eventstream.NewJournal(storage, cfg.EventRetention).Run(context.Background(), journalEvents)
+
+	feeCap := new(big.Int).Mul(new(big.Int).SetUint64(cfg.TxFeeCapGwei), big.NewInt(params.GWei))
+
+	backends := make(map[uint64]rpcservices.ChainBackend)
+	for _, chain := range chainRegistry.All() {
+		inspector := contractinspector.NewContractInspector(chain.ID, storage, chain.Client)
+
+		txFetcher := txfetcher.NewTxFetcher(chain.ID, storage, chain.Client, verifier.NewVerifier(chain.Client), txfetcher.Verification{
+			Inclusion: cfg.VerifyInclusion,
+			Receipts:  cfg.VerifyReceipts,
+		}, cfg.Confirmations, bus, inspector)
+		go chainwatcher.NewChainWatcher(chain.ID, storage, chain.Client, bus).Run(context.Background(), cfg.BlockPollTime)
+
+		txSender := txsender.NewTxSender(chain.ID, chain.Client, txFetcher, feeCap)
+
+		backend := rpcservices.ChainBackend{
+			TxFetcher: txFetcher,
+			Simulator: simulator.NewSimulator(chain.RPC),
+			TxSender:  txSender,
+			GasOracle: gasoracle.NewGasOracle(chain.Client),
+
+			AccountStateFetcher: accountstate.NewAccountStateFetcher(chain.ID, storage, chain.RPC),
+			Prover:              proofs.NewProver(chain.ID, chain.RPC, gethclient.New(chain.RPC)),
+			ContractInspector:   inspector,
+		}
+
+		if len(managedAddresses) > 0 {
+			nonceManager := noncemanager.NewNonceManager(chain.ID, storage, chain.Client, managedAddresses, cfg.NonceManagerUsers)
+			go nonceManager.WatchGaps(context.Background(), cfg.NonceReconcileInterval)
+			txSender.SetNonceManager(nonceManager)
+			backend.NonceManager = nonceManager
+		}
+
+		if registryAddress, ok := ens.RegistryAddress(chain.ID, chain.EnsRegistry); cfg.EnsEnabled && ok {
+			nameResolver, err := ens.NewResolver(chain.Client, registryAddress, cfg.EnsCacheTtl)
+			if err != nil {
+				return err
+			}
+			backend.NameResolver = nameResolver
+		}
+
+		if cfg.MempoolEnabled {
+			observer := mempool.NewObserver(chain.ID, chain.RPC, chain.Client, cfg.MempoolMaxTxs, cfg.MempoolMaxAge)
+			go observer.Run(context.Background())
+			txFetcher.SetMempool(observer)
+			backend.Mempool = observer
+		}
+
+		backends[chain.ID] = backend
+
+		go txFetcher.FollowPending(context.Background(), cfg.PendingPollTime)
+	}
 
 	auth := authenticator.NewAuthenticator(storage)
 
-	if err := server.RegisterService(rpcservices.NewLimeService(txFetcher, auth), ""); err != nil {
+	lime := rpcservices.NewLimeService(chainRegistry, backends, auth, storage, dispatcher)
+	if err := server.RegisterService(lime, ""); err != nil {
+		return err
+	}
+
+	discovery := rpcdiscovery.NewDiscovery("Lime JSON-RPC", "1.0.0")
+	if err := discovery.Describe(lime, ""); err != nil {
 		return err
 	}
 
-	http.Handle("/", server)
+	if err := server.RegisterService(discovery, "Rpc"); err != nil {
+		return err
+	}
+
+	rpcHandler := rpccodecs.NewHandler(server, cfg.RpcMaxBatchSize, cfg.RpcBatchConcurrency)
+
+	http.Handle("/", rpcHandler)
+	http.Handle("/graphql", graphqlapi.NewHandler(auth, storage, chainRegistry, backends))
+	http.Handle("/v1/", restgateway.NewGateway(auth, chainRegistry, backends))
+	http.Handle("/ws", rpcwebsocket.NewHandler(rpcHandler, auth, chainRegistry, bus))
+	http.Handle("/events", eventstream.NewHandler(auth, storage, cfg.EventStreamPollTime))
+	http.Handle("/export", exporter.NewHandler(auth, exporter.NewExporter(storage, chainRegistry)))
 
 	return http.ListenAndServe(fmt.Sprintf("localhost:%d", cfg.ApiPort), nil)
 }
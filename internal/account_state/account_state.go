@@ -0,0 +1,159 @@
+package accountstate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/avalkov/eth-node-interaction/internal/chains"
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func NewAccountStateFetcher(chainID uint64, storage storage, client client) *accountStateFetcher {
+	return &accountStateFetcher{
+		chainID: chainID,
+		storage: storage,
+		client:  client,
+	}
+}
+
+func (f *accountStateFetcher) FetchAccountState(ctx context.Context, address common.Address, block string) (model.AccountState, error) {
+	blockParam, err := chains.BlockParam(block)
+	if err != nil {
+		return model.AccountState{}, err
+	}
+
+	if blockParam == "pending" {
+		return model.AccountState{}, errors.New("account state of the pending block is not supported")
+	}
+
+	blockNumber, headNumber, err := f.resolveBlock(ctx, blockParam)
+	if err != nil {
+		return model.AccountState{}, err
+	}
+
+	isFinal := blockNumber+finalityDepth <= headNumber
+
+	if isFinal {
+		state, err := f.storage.GetAccountState(ctx, f.chainID, address.Hex(), blockNumber)
+		if err != nil {
+			log.Println(err)
+		}
+		if state != nil {
+			return *state, nil
+		}
+	}
+
+	numberParam := hexutil.EncodeUint64(blockNumber)
+
+	var balance hexutil.Big
+	if err := f.client.CallContext(ctx, &balance, "eth_getBalance", address, numberParam); err != nil {
+		return model.AccountState{}, stateError(blockNumber, err)
+	}
+
+	var nonce hexutil.Uint64
+	if err := f.client.CallContext(ctx, &nonce, "eth_getTransactionCount", address, numberParam); err != nil {
+		return model.AccountState{}, stateError(blockNumber, err)
+	}
+
+	var code hexutil.Bytes
+	if err := f.client.CallContext(ctx, &code, "eth_getCode", address, numberParam); err != nil {
+		return model.AccountState{}, stateError(blockNumber, err)
+	}
+
+	state := model.AccountState{
+		ChainID:     f.chainID,
+		Address:     address.Hex(),
+		BlockNumber: blockNumber,
+		Balance:     balance.ToInt().String(),
+		Nonce:       uint64(nonce),
+		CodeHash:    crypto.Keccak256Hash(code).Hex(),
+	}
+
+	// State of blocks that can still be reorged is not cached
+	if isFinal {
+		go func() {
+			ctxWithTimeout, cancelFunc := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancelFunc()
+			if err := f.storage.StoreAccountState(ctxWithTimeout, state); err != nil {
+				log.Println(fmt.Errorf("failed to store account state of (%s): %s", state.Address, err))
+			}
+		}()
+	}
+
+	return state, nil
+}
+
+func (f *accountStateFetcher) resolveBlock(ctx context.Context, blockParam string) (uint64, uint64, error) {
+	var head hexutil.Uint64
+	if err := f.client.CallContext(ctx, &head, "eth_blockNumber"); err != nil {
+		return 0, 0, err
+	}
+
+	if strings.HasPrefix(blockParam, "0x") {
+		blockNumber, err := hexutil.DecodeUint64(blockParam)
+		if err != nil {
+			return 0, 0, err
+		}
+		if blockNumber > uint64(head) {
+			return 0, 0, fmt.Errorf("block (%d) is ahead of the chain head (%d)", blockNumber, uint64(head))
+		}
+		return blockNumber, uint64(head), nil
+	}
+
+	var header *struct {
+		Number hexutil.Uint64 `json:"number"`
+	}
+	if err := f.client.CallContext(ctx, &header, "eth_getBlockByNumber", blockParam, false); err != nil {
+		return 0, 0, err
+	}
+	if header == nil {
+		return 0, 0, fmt.Errorf("block (%s) not found", blockParam)
+	}
+
+	return uint64(header.Number), uint64(head), nil
+}
+
+// Non-archive nodes prune historical state and report it with node specific messages.
+func stateError(blockNumber uint64, err error) error {
+	message := strings.ToLower(err.Error())
+	for _, marker := range missingStateMarkers {
+		if strings.Contains(message, marker) {
+			return fmt.Errorf("node lacks archive state for block (%d): %s", blockNumber, err)
+		}
+	}
+	return err
+}
+
+var missingStateMarkers = []string{
+	"missing trie node",
+	"state is not available",
+	"state not available",
+	"archive",
+	"pruned",
+	"historical state",
+}
+
+// Blocks this deep below the head are considered final.
+const finalityDepth = 64
+
+type storage interface {
+	GetAccountState(ctx context.Context, chainID uint64, address string, blockNumber uint64) (*model.AccountState, error)
+	StoreAccountState(ctx context.Context, state model.AccountState) error
+}
+
+type client interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+type accountStateFetcher struct {
+	chainID uint64
+	storage storage
+	client  client
+}
@@ -34,20 +34,25 @@ func (auth *authenticator) Authenticate(ctx context.Context, username, password
 }
 
 func (auth *authenticator) VerifyToken(token string) error {
+	_, err := auth.Username(token)
+	return err
+}
+
+func (auth *authenticator) Username(token string) (string, error) {
 	claims := &Claims{}
 	tkn, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
 		return jwtKey, nil
 	})
 
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	if !tkn.Valid {
-		return errors.New("invalid token")
+		return "", errors.New("invalid token")
 	}
 
-	return nil
+	return claims.Username, nil
 }
 
 type Claims struct {
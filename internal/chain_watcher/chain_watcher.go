@@ -0,0 +1,255 @@
+package chainwatcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func NewChainWatcher(chainID uint64, storage storage, client client, publisher publisher) *chainWatcher {
+	return &chainWatcher{
+		chainID:   chainID,
+		storage:   storage,
+		client:    client,
+		publisher: publisher,
+	}
+}
+
+// Run follows the chain head, announces every new block and matches its transactions and logs
+// against the users' watchlists. Matching starts from the head at the time of the call. A watcher
+// that fell behind catches up in batches without waiting for the next tick.
+func (cw *chainWatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		behind, err := cw.poll(ctx)
+		if err != nil {
+			log.Println(fmt.Errorf("chain watcher of chain (%d): %s", cw.chainID, err))
+		}
+
+		if behind && err == nil && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll processes at most maxCatchUpBlocks blocks and reports whether the head is still ahead.
+func (cw *chainWatcher) poll(ctx context.Context) (bool, error) {
+	head, err := cw.client.BlockNumber(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if cw.lastBlock == 0 || head < cw.lastBlock {
+		cw.lastBlock = head
+		return false, nil
+	}
+
+	to := min(head, cw.lastBlock+maxCatchUpBlocks)
+	for number := cw.lastBlock + 1; number <= to; number++ {
+		if err := cw.processBlock(ctx, number); err != nil {
+			return false, fmt.Errorf("failed to process block (%d): %s", number, err)
+		}
+		cw.lastBlock = number
+	}
+
+	return cw.lastBlock < head, nil
+}
+
+// The block is announced once its matches are stored, so a failing block is retried by the next
+// poll without being announced twice.
+func (cw *chainWatcher) processBlock(ctx context.Context, number uint64) error {
+	block, err := cw.client.BlockByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UnixNano()
+
+	if err := cw.matchWatchlists(ctx, block, now); err != nil {
+		return err
+	}
+
+	cw.publisher.Publish(model.Event{
+		Type:      model.NewBlockEvent,
+		ChainID:   cw.chainID,
+		CreatedAt: now,
+		Block: &model.Block{
+			ChainID:           cw.chainID,
+			Number:            number,
+			Hash:              block.Hash().Hex(),
+			ParentHash:        block.ParentHash().Hex(),
+			Timestamp:         block.Time(),
+			TransactionsCount: len(block.Transactions()),
+		},
+	})
+
+	return nil
+}
+
+func (cw *chainWatcher) matchWatchlists(ctx context.Context, block *types.Block, now int64) error {
+	watched, err := cw.storage.GetWatchedAddresses(ctx, cw.chainID)
+	if err != nil {
+		return err
+	}
+
+	if len(watched) == 0 {
+		return nil
+	}
+
+	watchers := make(map[common.Address][]string)
+	for _, watchedAddress := range watched {
+		address := common.HexToAddress(watchedAddress.Address)
+		watchers[address] = append(watchers[address], watchedAddress.Username)
+	}
+
+	logs, err := cw.client.FilterLogs(ctx, ethereum.FilterQuery{FromBlock: block.Number(), ToBlock: block.Number()})
+	if err != nil {
+		return err
+	}
+
+	// Logs are queried by number, a reorg in between leaves them from another block
+	for _, txLog := range logs {
+		if txLog.BlockHash != block.Hash() {
+			return fmt.Errorf("logs of block (%d) are from block (%s), it was reorged", block.NumberU64(), txLog.BlockHash.Hex())
+		}
+	}
+
+	notifications := []model.Notification{}
+
+	notify := func(address common.Address, kind model.NotificationKind, txHash common.Hash, logIndex int) {
+		for _, username := range watchers[address] {
+			notifications = append(notifications, model.Notification{
+				Username:        username,
+				ChainID:         cw.chainID,
+				Address:         address.Hex(),
+				Kind:            kind,
+				TransactionHash: txHash.Hex(),
+				BlockNumber:     block.NumberU64(),
+				LogIndex:        logIndex,
+				CreatedAt:       now,
+			})
+		}
+	}
+
+	for _, tx := range block.Transactions() {
+		for _, address := range txAddresses(tx) {
+			notify(address, model.TransactionNotification, tx.Hash(), -1)
+		}
+	}
+
+	for _, txLog := range logs {
+		for _, address := range logAddresses(txLog) {
+			notify(address, model.LogNotification, txLog.TxHash, int(txLog.Index))
+		}
+	}
+
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	stored, err := cw.storage.StoreNotifications(ctx, notifications)
+	if err != nil {
+		return err
+	}
+
+	for i := range stored {
+		cw.publisher.Publish(model.Event{
+			Type:         model.WatchMatchEvent,
+			Username:     stored[i].Username,
+			ChainID:      cw.chainID,
+			CreatedAt:    now,
+			Notification: &stored[i],
+		})
+	}
+
+	return nil
+}
+
+func txAddresses(tx *types.Transaction) []common.Address {
+	addresses := []common.Address{}
+
+	if from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx); err == nil {
+		addresses = append(addresses, from)
+	}
+
+	if tx.To() != nil {
+		addresses = append(addresses, *tx.To())
+	}
+
+	return unique(addresses)
+}
+
+// Besides the emitting contract, indexed address parameters (e.g. ERC-20 Transfer
+// from/to) are matched. They are stored as topics left padded with zeroes.
+func logAddresses(txLog types.Log) []common.Address {
+	addresses := []common.Address{txLog.Address}
+
+	for i := 1; i < len(txLog.Topics); i++ {
+		if isZeroPadded(txLog.Topics[i]) {
+			addresses = append(addresses, common.BytesToAddress(txLog.Topics[i].Bytes()))
+		}
+	}
+
+	return unique(addresses)
+}
+
+func isZeroPadded(topic common.Hash) bool {
+	for _, b := range topic.Bytes()[:12] {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func unique(addresses []common.Address) []common.Address {
+	seen := make(map[common.Address]struct{})
+	result := []common.Address{}
+	for _, address := range addresses {
+		if _, ok := seen[address]; !ok {
+			seen[address] = struct{}{}
+			result = append(result, address)
+		}
+	}
+	return result
+}
+
+const maxCatchUpBlocks = 100
+
+type storage interface {
+	GetWatchedAddresses(ctx context.Context, chainID uint64) ([]model.WatchedAddress, error)
+	StoreNotifications(ctx context.Context, notifications []model.Notification) ([]model.Notification, error)
+}
+
+type client interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+}
+
+type publisher interface {
+	Publish(event model.Event)
+}
+
+type chainWatcher struct {
+	chainID   uint64
+	storage   storage
+	client    client
+	publisher publisher
+	lastBlock uint64
+}
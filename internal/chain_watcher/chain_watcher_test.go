@@ -0,0 +1,109 @@
+package chainwatcher
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestPollCatchesUpInBatches(t *testing.T) {
+	client := &fakeClient{head: 10}
+	publisher := &fakePublisher{}
+	cw := NewChainWatcher(1, fakeStorage{}, client, publisher)
+
+	if behind, err := cw.poll(context.Background()); err != nil || behind {
+		t.Fatalf("first poll = %t, %v", behind, err)
+	}
+
+	client.head = 10 + 2*maxCatchUpBlocks + 5
+
+	polls := 0
+	for behind := true; behind; polls++ {
+		var err error
+		if behind, err = cw.poll(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if polls != 3 {
+		t.Fatalf("caught up in %d polls, want 3", polls)
+	}
+	if len(publisher.events) != 2*maxCatchUpBlocks+5 {
+		t.Fatalf("%d blocks announced, want %d", len(publisher.events), 2*maxCatchUpBlocks+5)
+	}
+	for i, event := range publisher.events {
+		if event.Block.Number != uint64(11+i) {
+			t.Fatalf("block %d announced as %d", 11+i, event.Block.Number)
+		}
+	}
+}
+
+func TestPollRetriesFailedBlocks(t *testing.T) {
+	client := &fakeClient{head: 10}
+	publisher := &fakePublisher{}
+	cw := NewChainWatcher(1, fakeStorage{}, client, publisher)
+	cw.poll(context.Background())
+
+	client.head = 15
+	client.failAt = 13
+	if _, err := cw.poll(context.Background()); err == nil {
+		t.Fatal("poll succeeded with a failing block")
+	}
+
+	client.failAt = 0
+	if behind, err := cw.poll(context.Background()); err != nil || behind {
+		t.Fatalf("poll = %t, %v", behind, err)
+	}
+
+	want := []uint64{11, 12, 13, 14, 15}
+	if len(publisher.events) != len(want) {
+		t.Fatalf("%d blocks announced, want %d", len(publisher.events), len(want))
+	}
+	for i, event := range publisher.events {
+		if event.Block.Number != want[i] {
+			t.Fatalf("announced block %d, want %d", event.Block.Number, want[i])
+		}
+	}
+}
+
+type fakeClient struct {
+	head   uint64
+	failAt uint64
+}
+
+func (c *fakeClient) BlockNumber(ctx context.Context) (uint64, error) {
+	return c.head, nil
+}
+
+func (c *fakeClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	if number.Uint64() == c.failAt {
+		return nil, ethereum.NotFound
+	}
+	return types.NewBlockWithHeader(&types.Header{Number: number}), nil
+}
+
+func (c *fakeClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	return nil, nil
+}
+
+type fakeStorage struct{}
+
+func (fakeStorage) GetWatchedAddresses(ctx context.Context, chainID uint64) ([]model.WatchedAddress, error) {
+	return nil, nil
+}
+
+func (fakeStorage) StoreNotifications(ctx context.Context, notifications []model.Notification) ([]model.Notification, error) {
+	return notifications, nil
+}
+
+type fakePublisher struct {
+	events []model.Event
+}
+
+func (p *fakePublisher) Publish(event model.Event) {
+	p.events = append(p.events, event)
+}
@@ -0,0 +1,37 @@
+package chains
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// BlockParam converts a block number (decimal or hex) or tag into the form expected
+// by the node JSON-RPC API. An empty block means "latest".
+func BlockParam(block string) (string, error) {
+	block = strings.ToLower(strings.TrimSpace(block))
+
+	switch block {
+	case "":
+		return "latest", nil
+	case "latest", "pending", "earliest", "safe", "finalized":
+		return block, nil
+	}
+
+	if strings.HasPrefix(block, "0x") {
+		number, err := hexutil.DecodeUint64(block)
+		if err != nil {
+			return "", fmt.Errorf("invalid block (%s): %s", block, err)
+		}
+		return hexutil.EncodeUint64(number), nil
+	}
+
+	number, err := strconv.ParseUint(block, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid block (%s)", block)
+	}
+
+	return hexutil.EncodeUint64(number), nil
+}
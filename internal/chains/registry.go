@@ -0,0 +1,135 @@
+package chains
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/avalkov/eth-node-interaction/internal/config"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func NewRegistry(ctx context.Context, chainConfigs []config.ChainConfig, defaultChain string) (*registry, error) {
+	if len(chainConfigs) == 0 {
+		return nil, fmt.Errorf("no chains configured")
+	}
+
+	reg := &registry{byID: make(map[uint64]*Chain)}
+
+	for _, chainConfig := range chainConfigs {
+		chain, err := dialChain(ctx, chainConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := reg.byID[chain.ID]; ok {
+			return nil, fmt.Errorf("chain (%d) is configured more than once", chain.ID)
+		}
+
+		reg.byID[chain.ID] = chain
+		reg.chains = append(reg.chains, chain)
+	}
+
+	reg.defaultChain = reg.chains[0]
+	if defaultChain != "" {
+		chain, err := reg.Get(defaultChain)
+		if err != nil {
+			return nil, err
+		}
+		reg.defaultChain = chain
+	}
+
+	return reg, nil
+}
+
+// Get accepts a chain ID or a chain name. An empty selector picks the default chain.
+func (reg *registry) Get(selector string) (*Chain, error) {
+	selector = strings.TrimSpace(selector)
+	if selector == "" {
+		return reg.defaultChain, nil
+	}
+
+	if id, err := strconv.ParseUint(selector, 10, 64); err == nil {
+		if chain, ok := reg.byID[id]; ok {
+			return chain, nil
+		}
+		return nil, fmt.Errorf("unknown chain (%d)", id)
+	}
+
+	for _, chain := range reg.chains {
+		if strings.EqualFold(chain.Name, selector) {
+			return chain, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown chain (%s)", selector)
+}
+
+func (reg *registry) ChainID(selector string) (uint64, error) {
+	chain, err := reg.Get(selector)
+	if err != nil {
+		return 0, err
+	}
+	return chain.ID, nil
+}
+
+func (reg *registry) All() []*Chain {
+	return reg.chains
+}
+
+func (reg *registry) Default() *Chain {
+	return reg.defaultChain
+}
+
+// The node endpoints are tried in order and the first one that answers with
+// the expected chain ID is used.
+func dialChain(ctx context.Context, chainConfig config.ChainConfig) (*Chain, error) {
+	for _, nodeUrl := range chainConfig.NodeUrls {
+		rpcClient, err := rpc.DialContext(ctx, nodeUrl)
+		if err != nil {
+			log.Println(fmt.Errorf("failed to dial node of chain (%s): %s", chainConfig.Name, err))
+			continue
+		}
+
+		client := ethclient.NewClient(rpcClient)
+
+		chainID, err := client.ChainID(ctx)
+		if err != nil {
+			log.Println(fmt.Errorf("failed to get chain id from node of chain (%s): %s", chainConfig.Name, err))
+			rpcClient.Close()
+			continue
+		}
+
+		if chainConfig.ID != 0 && chainID.Uint64() != chainConfig.ID {
+			rpcClient.Close()
+			return nil, fmt.Errorf("node of chain (%s) reports chain id %d, expected %d", chainConfig.Name, chainID.Uint64(), chainConfig.ID)
+		}
+
+		return &Chain{
+			ID:          chainID.Uint64(),
+			Name:        chainConfig.Name,
+			Client:      client,
+			RPC:         rpcClient,
+			EnsRegistry: chainConfig.EnsRegistry,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no reachable node for chain (%s)", chainConfig.Name)
+}
+
+type Chain struct {
+	ID          uint64
+	Name        string
+	Client      *ethclient.Client
+	RPC         *rpc.Client
+	EnsRegistry string
+}
+
+type registry struct {
+	chains       []*Chain
+	byID         map[uint64]*Chain
+	defaultChain *Chain
+}
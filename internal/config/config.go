@@ -1,9 +1,13 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/joho/godotenv"
 )
 
@@ -12,11 +16,123 @@ func NewConfig(envPath string) (Config, error) {
 		return Config{}, err
 	}
 
-	return Config{
+	chains, err := parseChains(getEnv("CHAINS", ""), getEnv("ETH_NODE_URL", ""))
+	if err != nil {
+		return Config{}, err
+	}
+
+	if err := parseEnsRegistries(getEnv("ENS_REGISTRIES", ""), chains); err != nil {
+		return Config{}, err
+	}
+
+	cfg := Config{
 		ApiPort:         getEnvAsInt("API_PORT", 31337),
-		EthNodeUrl:      getEnv("ETH_NODE_URL", ""),
+		Chains:          chains,
+		DefaultChain:    getEnv("DEFAULT_CHAIN", ""),
 		DbConnectionUrl: getEnv("DB_CONNECTION_URL", ""),
-	}, nil
+		EnsEnabled:      getEnvAsBool("ENS_ENABLED", false),
+		EnsCacheTtl:     time.Duration(getEnvAsInt("ENS_CACHE_TTL_SECONDS", 3600)) * time.Second,
+		TxFeeCapGwei:    uint64(getEnvAsInt("TX_FEE_CAP_GWEI", 1000000000)),
+		PendingPollTime: time.Duration(getEnvAsInt("PENDING_POLL_INTERVAL_SECONDS", 15)) * time.Second,
+		BlockPollTime:   time.Duration(getEnvAsInt("BLOCK_POLL_INTERVAL_SECONDS", 12)) * time.Second,
+		VerifyInclusion: getEnvAsBool("VERIFY_INCLUSION", false),
+		VerifyReceipts:  getEnvAsBool("VERIFY_RECEIPTS", false),
+		Confirmations:   uint64(getEnvAsInt("CONFIRMATIONS", 12)),
+
+		ManagedAddresses:       getEnvAsList("MANAGED_ADDRESSES"),
+		NonceManagerUsers:      getEnvAsList("NONCE_MANAGER_USERS"),
+		NonceReconcileInterval: time.Duration(getEnvAsInt("NONCE_RECONCILE_INTERVAL_SECONDS", 60)) * time.Second,
+
+		WebhookPollTime: time.Duration(getEnvAsInt("WEBHOOK_POLL_INTERVAL_SECONDS", 5)) * time.Second,
+		WebhookTimeout:  time.Duration(getEnvAsInt("WEBHOOK_TIMEOUT_SECONDS", 10)) * time.Second,
+
+		EventStreamPollTime: time.Duration(getEnvAsInt("EVENT_STREAM_POLL_INTERVAL_SECONDS", 1)) * time.Second,
+		EventRetention:      time.Duration(getEnvAsInt("EVENT_RETENTION_HOURS", 168)) * time.Hour,
+
+		MempoolEnabled: getEnvAsBool("MEMPOOL_ENABLED", false),
+		MempoolMaxTxs:  getEnvAsInt("MEMPOOL_MAX_TXS", 50000),
+		MempoolMaxAge:  time.Duration(getEnvAsInt("MEMPOOL_MAX_AGE_MINUTES", 60)) * time.Minute,
+
+		RpcMaxBatchSize:     getEnvAsInt("RPC_MAX_BATCH_SIZE", 100),
+		RpcBatchConcurrency: getEnvAsInt("RPC_BATCH_CONCURRENCY", 8),
+	}
+
+	if err := cfg.validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// validate rejects settings that would make the service reject every request of a kind.
+func (c Config) validate() error {
+	if c.RpcMaxBatchSize < 1 {
+		return fmt.Errorf("RPC_MAX_BATCH_SIZE must be at least 1, got %d", c.RpcMaxBatchSize)
+	}
+	if c.RpcBatchConcurrency < 1 {
+		return fmt.Errorf("RPC_BATCH_CONCURRENCY must be at least 1, got %d", c.RpcBatchConcurrency)
+	}
+	return nil
+}
+
+// Chains are declared as "name:chainId:url1|url2" entries separated by commas.
+// Without CHAINS the single ETH_NODE_URL is used and its chain ID is taken from the node.
+func parseChains(value, fallbackNodeUrl string) ([]ChainConfig, error) {
+	if strings.TrimSpace(value) == "" {
+		if fallbackNodeUrl == "" {
+			return nil, fmt.Errorf("neither CHAINS nor ETH_NODE_URL is set")
+		}
+		return []ChainConfig{{Name: "default", NodeUrls: []string{fallbackNodeUrl}}}, nil
+	}
+
+	chains := []ChainConfig{}
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid chain entry: %q", entry)
+		}
+
+		id, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chain id in entry %q: %s", entry, err)
+		}
+
+		chains = append(chains, ChainConfig{
+			ID:       id,
+			Name:     parts[0],
+			NodeUrls: strings.Split(parts[2], "|"),
+		})
+	}
+
+	return chains, nil
+}
+
+// ENS registries are declared as "chainName:registryAddress" entries separated by commas. Chains
+// without an entry use the registry ENS deployed on them, if any.
+func parseEnsRegistries(value string, chains []ChainConfig) error {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+		if len(parts) != 2 || !common.IsHexAddress(parts[1]) {
+			return fmt.Errorf("invalid ens registry entry: %q", entry)
+		}
+
+		found := false
+		for i := range chains {
+			if chains[i].Name == parts[0] {
+				chains[i].EnsRegistry = parts[1]
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("ens registry entry %q names an unknown chain", entry)
+		}
+	}
+
+	return nil
 }
 
 func getEnv(key string, defaultVal string) string {
@@ -34,8 +150,59 @@ func getEnvAsInt(name string, defaultVal int) int {
 	return defaultVal
 }
 
+func getEnvAsBool(name string, defaultVal bool) bool {
+	valueStr := getEnv(name, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultVal
+}
+
+func getEnvAsList(name string) []string {
+	values := []string{}
+	for _, value := range strings.Split(getEnv(name, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+type ChainConfig struct {
+	ID          uint64
+	Name        string
+	NodeUrls    []string
+	EnsRegistry string
+}
+
 type Config struct {
 	ApiPort         int
-	EthNodeUrl      string
+	Chains          []ChainConfig
+	DefaultChain    string
 	DbConnectionUrl string
+	EnsEnabled      bool
+	EnsCacheTtl     time.Duration
+	TxFeeCapGwei    uint64
+	PendingPollTime time.Duration
+	BlockPollTime   time.Duration
+	VerifyInclusion bool
+	VerifyReceipts  bool
+	Confirmations   uint64
+
+	ManagedAddresses       []string
+	NonceManagerUsers      []string
+	NonceReconcileInterval time.Duration
+
+	WebhookPollTime time.Duration
+	WebhookTimeout  time.Duration
+
+	EventStreamPollTime time.Duration
+	EventRetention      time.Duration
+
+	MempoolEnabled bool
+	MempoolMaxTxs  int
+	MempoolMaxAge  time.Duration
+
+	RpcMaxBatchSize     int
+	RpcBatchConcurrency int
 }
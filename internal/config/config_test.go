@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewConfigValidatesBatchSettings(t *testing.T) {
+	tests := []struct {
+		name             string
+		maxBatchSize     string
+		batchConcurrency string
+		wantErr          bool
+	}{
+		{"defaults", "", "", false},
+		{"smallest values", "1", "1", false},
+		{"zero batch size", "0", "", true},
+		{"negative batch size", "-5", "", true},
+		{"zero concurrency", "", "0", true},
+		{"negative concurrency", "10", "-1", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			envPath := filepath.Join(t.TempDir(), ".env")
+			if err := os.WriteFile(envPath, nil, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("ETH_NODE_URL", "http://localhost:8545")
+			t.Setenv("RPC_MAX_BATCH_SIZE", test.maxBatchSize)
+			t.Setenv("RPC_BATCH_CONCURRENCY", test.batchConcurrency)
+
+			cfg, err := NewConfig(envPath)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("config accepted with batch size %d and concurrency %d", cfg.RpcMaxBatchSize, cfg.RpcBatchConcurrency)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.RpcMaxBatchSize < 1 || cfg.RpcBatchConcurrency < 1 {
+				t.Fatalf("batch size %d and concurrency %d", cfg.RpcMaxBatchSize, cfg.RpcBatchConcurrency)
+			}
+		})
+	}
+}
+
+func TestParseEnsRegistries(t *testing.T) {
+	const registry = "0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e"
+
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"none", "", map[string]string{"mainnet": "", "base": ""}, false},
+		{"one chain", "base:" + registry, map[string]string{"mainnet": "", "base": registry}, false},
+		{"unknown chain", "optimism:" + registry, nil, true},
+		{"invalid address", "base:0x1234", nil, true},
+		{"missing address", "base", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chains := []ChainConfig{{ID: 1, Name: "mainnet"}, {ID: 8453, Name: "base"}}
+
+			err := parseEnsRegistries(test.value, chains)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("entries %q accepted", test.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, chain := range chains {
+				if chain.EnsRegistry != test.want[chain.Name] {
+					t.Fatalf("registry of %s = %q, want %q", chain.Name, chain.EnsRegistry, test.want[chain.Name])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package contractinspector
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func NewContractInspector(chainID uint64, storage storage, client client) *contractInspector {
+	return &contractInspector{
+		chainID: chainID,
+		storage: storage,
+		client:  client,
+	}
+}
+
+// Inspect reads the code and the proxy slots of the contract at the head. The result is stored,
+// as the implementation of upgradeable proxies changes over time.
+func (ci *contractInspector) Inspect(ctx context.Context, address common.Address) (model.ContractInfo, error) {
+	return ci.inspect(ctx, address, nil)
+}
+
+// RecordCreation inspects a contract deployed by the transaction, unless it is already known.
+func (ci *contractInspector) RecordCreation(ctx context.Context, address common.Address, txHash string) error {
+	stored, err := ci.storage.GetContract(ctx, ci.chainID, address.Hex())
+	if err != nil {
+		return err
+	}
+
+	if stored != nil && stored.CreationTransactionHash != nil {
+		return nil
+	}
+
+	_, err = ci.inspect(ctx, address, &txHash)
+	return err
+}
+
+func (ci *contractInspector) inspect(ctx context.Context, address common.Address, creationTxHash *string) (model.ContractInfo, error) {
+	head, err := ci.client.BlockNumber(ctx)
+	if err != nil {
+		return model.ContractInfo{}, err
+	}
+	block := new(big.Int).SetUint64(head)
+
+	code, err := ci.client.CodeAt(ctx, address, block)
+	if err != nil {
+		return model.ContractInfo{}, err
+	}
+
+	if len(code) == 0 {
+		return model.ContractInfo{}, fmt.Errorf("no contract code at (%s)", address.Hex())
+	}
+
+	info := model.ContractInfo{
+		ChainID:                 ci.chainID,
+		Address:                 address.Hex(),
+		CodeHash:                crypto.Keccak256Hash(code).Hex(),
+		CodeSize:                len(code),
+		ProxyType:               model.NoProxy,
+		CreationTransactionHash: creationTxHash,
+		BlockNumber:             head,
+		UpdatedAt:               time.Now().UnixNano(),
+	}
+
+	if err := ci.detectProxy(ctx, address, code, block, &info); err != nil {
+		return model.ContractInfo{}, err
+	}
+
+	if err := ci.storage.StoreContract(ctx, info); err != nil {
+		return model.ContractInfo{}, err
+	}
+
+	if stored, err := ci.storage.GetContract(ctx, ci.chainID, info.Address); err == nil && stored != nil {
+		info.CreationTransactionHash = stored.CreationTransactionHash
+	}
+
+	info.AbiAddress = info.Address
+	if info.Implementation != nil {
+		info.AbiAddress = *info.Implementation
+	}
+
+	return info, nil
+}
+
+func (ci *contractInspector) detectProxy(ctx context.Context, address common.Address, code []byte, block *big.Int, info *model.ContractInfo) error {
+	if implementation, ok := minimalProxyTarget(code); ok {
+		info.ProxyType = model.Eip1167MinimalProxy
+		info.Implementation = addressPointer(implementation)
+		return nil
+	}
+
+	admin, err := ci.slotAddress(ctx, address, adminSlot, block)
+	if err != nil {
+		return err
+	}
+	info.Admin = admin
+
+	implementation, err := ci.slotAddress(ctx, address, implementationSlot, block)
+	if err != nil {
+		return err
+	}
+
+	if implementation != nil {
+		info.ProxyType = model.Eip1967Proxy
+		info.Implementation = implementation
+		return nil
+	}
+
+	beacon, err := ci.slotAddress(ctx, address, beaconSlot, block)
+	if err != nil {
+		return err
+	}
+
+	if beacon == nil {
+		return nil
+	}
+
+	beaconAddress := common.HexToAddress(*beacon)
+	result, err := ci.client.CallContract(ctx, ethereum.CallMsg{To: &beaconAddress, Data: implementationSelector}, block)
+	if err != nil {
+		return fmt.Errorf("failed to get implementation of beacon (%s): %s", *beacon, err)
+	}
+
+	if len(result) != 32 {
+		return fmt.Errorf("beacon (%s) returned invalid implementation", *beacon)
+	}
+
+	info.ProxyType = model.Eip1967BeaconProxy
+	info.Beacon = beacon
+	info.Implementation = addressPointer(common.BytesToAddress(result))
+
+	return nil
+}
+
+// slotAddress returns nil for empty slots.
+func (ci *contractInspector) slotAddress(ctx context.Context, address common.Address, slot common.Hash, block *big.Int) (*string, error) {
+	value, err := ci.client.StorageAt(ctx, address, slot, block)
+	if err != nil {
+		return nil, err
+	}
+
+	slotAddress := common.BytesToAddress(value)
+	if slotAddress == (common.Address{}) {
+		return nil, nil
+	}
+
+	return addressPointer(slotAddress), nil
+}
+
+// EIP-1167 clones are a fixed bytecode with the target address in the middle.
+func minimalProxyTarget(code []byte) (common.Address, bool) {
+	if len(code) != len(minimalProxyPrefix)+common.AddressLength+len(minimalProxySuffix) {
+		return common.Address{}, false
+	}
+
+	if !bytes.HasPrefix(code, minimalProxyPrefix) || !bytes.HasSuffix(code, minimalProxySuffix) {
+		return common.Address{}, false
+	}
+
+	return common.BytesToAddress(code[len(minimalProxyPrefix) : len(minimalProxyPrefix)+common.AddressLength]), true
+}
+
+func addressPointer(address common.Address) *string {
+	hex := address.Hex()
+	return &hex
+}
+
+var (
+	// keccak256("eip1967.proxy.implementation") - 1
+	implementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+	// keccak256("eip1967.proxy.beacon") - 1
+	beaconSlot = common.HexToHash("0xa3f0ad74e5423aebfd80d3ef4346578335a9a72aeaee59ff6cb3582b35133d50")
+	// keccak256("eip1967.proxy.admin") - 1
+	adminSlot = common.HexToHash("0xb53127684a568b3173ae13b9f8a6016e243e63b6e8ee1178d6a717850b5d6103")
+
+	// implementation()
+	implementationSelector = common.FromHex("0x5c60da1b")
+
+	minimalProxyPrefix = common.FromHex("0x363d3d373d3d3d363d73")
+	minimalProxySuffix = common.FromHex("0x5af43d82803e903d91602b57fd5bf3")
+)
+
+type storage interface {
+	GetContract(ctx context.Context, chainID uint64, address string) (*model.ContractInfo, error)
+	StoreContract(ctx context.Context, contract model.ContractInfo) error
+}
+
+type client interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+	CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+type contractInspector struct {
+	chainID uint64
+	storage storage
+	client  client
+}
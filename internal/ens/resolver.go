@@ -0,0 +1,210 @@
+package ens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func NewResolver(client client, registryAddress common.Address, ttl time.Duration) (*resolver, error) {
+	registryABI, err := abi.JSON(strings.NewReader(registryABIJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	resolverABI, err := abi.JSON(strings.NewReader(resolverABIJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	return &resolver{
+		client:      client,
+		registry:    registryAddress,
+		ttl:         ttl,
+		registryABI: registryABI,
+		resolverABI: resolverABI,
+		names:       lru.NewCache[common.Address, cacheEntry](namesCacheSize),
+	}, nil
+}
+
+// The reverse record is trusted only when the forward resolution points back
+// to the same address.
+// RegistryAddress returns the configured registry of the chain, otherwise the one ENS deployed on it.
+func RegistryAddress(chainID uint64, configured string) (common.Address, bool) {
+	if configured != "" {
+		return common.HexToAddress(configured), true
+	}
+
+	address, ok := deployedRegistries[chainID]
+	return address, ok
+}
+
+func (r *resolver) LookupAddress(ctx context.Context, address common.Address) (string, error) {
+	if entry, ok := r.cached(address); ok {
+		return entry.name, entry.err
+	}
+
+	name, err := r.reverse(ctx, address)
+	if err != nil {
+		// Failures are kept briefly, so a node that is down is not asked again for every request
+		r.names.Add(address, cacheEntry{err: err, expiresAt: time.Now().Add(failureTtl)})
+		return "", err
+	}
+
+	if name != "" {
+		resolved, err := r.ResolveName(ctx, name)
+		if err != nil || resolved != address {
+			name = ""
+		}
+	}
+
+	r.names.Add(address, cacheEntry{name: name, expiresAt: time.Now().Add(r.ttl)})
+
+	return name, nil
+}
+
+func (r *resolver) ResolveName(ctx context.Context, name string) (common.Address, error) {
+	node := namehash(name)
+
+	resolverAddress, err := r.resolverOf(ctx, node)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	var address common.Address
+	if err := r.call(ctx, resolverAddress, r.resolverABI, "addr", &address, [32]byte(node)); err != nil {
+		return common.Address{}, err
+	}
+
+	if address == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("name (%s) does not resolve to an address", name)
+	}
+
+	return address, nil
+}
+
+func (r *resolver) reverse(ctx context.Context, address common.Address) (string, error) {
+	node := namehash(strings.ToLower(address.Hex()[2:]) + ".addr.reverse")
+
+	resolverAddress, err := r.resolverOf(ctx, node)
+	if errors.Is(err, errNoResolver) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var name string
+	if err := r.call(ctx, resolverAddress, r.resolverABI, "name", &name, [32]byte(node)); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+func (r *resolver) resolverOf(ctx context.Context, node common.Hash) (common.Address, error) {
+	var resolverAddress common.Address
+	if err := r.call(ctx, r.registry, r.registryABI, "resolver", &resolverAddress, [32]byte(node)); err != nil {
+		return common.Address{}, err
+	}
+
+	if resolverAddress == (common.Address{}) {
+		return common.Address{}, errNoResolver
+	}
+
+	return resolverAddress, nil
+}
+
+func (r *resolver) call(ctx context.Context, to common.Address, contractABI abi.ABI, method string, result interface{}, args ...interface{}) error {
+	data, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return err
+	}
+
+	output, err := r.client.CallContract(ctx, ethereum.CallMsg{To: &to, Data: data}, nil)
+	if err != nil {
+		return err
+	}
+
+	values, err := contractABI.Unpack(method, output)
+	if err != nil {
+		return err
+	}
+
+	if len(values) == 0 {
+		return fmt.Errorf("empty response from %s", method)
+	}
+
+	return contractABI.Methods[method].Outputs.Copy(result, values)
+}
+
+func (r *resolver) cached(address common.Address) (cacheEntry, bool) {
+	entry, ok := r.names.Get(address)
+	if !ok || time.Now().After(entry.expiresAt) {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+func namehash(name string) common.Hash {
+	node := common.Hash{}
+	if name == "" {
+		return node
+	}
+
+	labels := strings.Split(strings.ToLower(name), ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		node = crypto.Keccak256Hash(node.Bytes(), crypto.Keccak256([]byte(labels[i])))
+	}
+	return node
+}
+
+// Mainnet, Sepolia and Holesky share the address of the registry
+var deployedRegistries = map[uint64]common.Address{
+	1:        common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e"),
+	11155111: common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e"),
+	17000:    common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e"),
+}
+
+var errNoResolver = errors.New("no resolver set")
+
+const (
+	namesCacheSize = 10000
+	failureTtl     = 30 * time.Second
+)
+
+const registryABIJSON = `[{"name":"resolver","type":"function","stateMutability":"view",
+"inputs":[{"name":"node","type":"bytes32"}],"outputs":[{"name":"","type":"address"}]}]`
+
+const resolverABIJSON = `[{"name":"name","type":"function","stateMutability":"view",
+"inputs":[{"name":"node","type":"bytes32"}],"outputs":[{"name":"","type":"string"}]},
+{"name":"addr","type":"function","stateMutability":"view",
+"inputs":[{"name":"node","type":"bytes32"}],"outputs":[{"name":"","type":"address"}]}]`
+
+type cacheEntry struct {
+	name      string
+	err       error
+	expiresAt time.Time
+}
+
+type client interface {
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+type resolver struct {
+	client      client
+	registry    common.Address
+	ttl         time.Duration
+	registryABI abi.ABI
+	resolverABI abi.ABI
+	names       *lru.Cache[common.Address, cacheEntry]
+}
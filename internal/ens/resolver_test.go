@@ -0,0 +1,144 @@
+package ens
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestNamehash(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"", "0x0000000000000000000000000000000000000000000000000000000000000000"},
+		{"eth", "0x93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae"},
+		{"foo.eth", "0xde9b09fd7c5f901e23a3f19fecc54828e9c848539801e86591bd9801b019f84f"},
+		{"Foo.ETH", "0xde9b09fd7c5f901e23a3f19fecc54828e9c848539801e86591bd9801b019f84f"},
+	}
+
+	for _, test := range tests {
+		if got := namehash(test.name).Hex(); got != test.want {
+			t.Errorf("namehash(%q) = %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLookupAddressCachesNames(t *testing.T) {
+	address := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	client := &fakeClient{name: "alice.eth", address: address}
+	r := newTestResolver(t, client)
+
+	for i := 0; i < 3; i++ {
+		name, err := r.LookupAddress(context.Background(), address)
+		if err != nil || name != "alice.eth" {
+			t.Fatalf("LookupAddress = %q, %v", name, err)
+		}
+	}
+
+	// The reverse and the forward resolution each ask the registry and the resolver
+	if client.calls != 4 {
+		t.Fatalf("%d node calls, want 4", client.calls)
+	}
+}
+
+func TestLookupAddressIgnoresUnverifiedNames(t *testing.T) {
+	client := &fakeClient{name: "alice.eth", address: common.HexToAddress("0x2222222222222222222222222222222222222222")}
+	r := newTestResolver(t, client)
+
+	name, err := r.LookupAddress(context.Background(), common.HexToAddress("0x1111111111111111111111111111111111111111"))
+	if err != nil || name != "" {
+		t.Fatalf("LookupAddress = %q, %v, want no name", name, err)
+	}
+}
+
+func TestLookupAddressCachesFailuresBriefly(t *testing.T) {
+	address := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	client := &fakeClient{err: errors.New("node is down")}
+	r := newTestResolver(t, client)
+
+	for i := 0; i < 3; i++ {
+		if _, err := r.LookupAddress(context.Background(), address); err == nil {
+			t.Fatal("LookupAddress succeeded with a failing node")
+		}
+	}
+	if client.calls != 1 {
+		t.Fatalf("%d node calls, want 1", client.calls)
+	}
+
+	entry, _ := r.names.Get(address)
+	if ttl := time.Until(entry.expiresAt); ttl > failureTtl {
+		t.Fatalf("failure is cached for %s, want at most %s", ttl, failureTtl)
+	}
+}
+
+func TestRegistryAddress(t *testing.T) {
+	configured := "0x3333333333333333333333333333333333333333"
+
+	tests := []struct {
+		chainID    uint64
+		configured string
+		want       common.Address
+		wantOk     bool
+	}{
+		{1, "", deployedRegistries[1], true},
+		{11155111, "", deployedRegistries[11155111], true},
+		{8453, "", common.Address{}, false},
+		{8453, configured, common.HexToAddress(configured), true},
+		{1, configured, common.HexToAddress(configured), true},
+	}
+
+	for _, test := range tests {
+		got, ok := RegistryAddress(test.chainID, test.configured)
+		if got != test.want || ok != test.wantOk {
+			t.Errorf("RegistryAddress(%d, %q) = %s, %t", test.chainID, test.configured, got.Hex(), ok)
+		}
+	}
+}
+
+func newTestResolver(t *testing.T, client *fakeClient) *resolver {
+	t.Helper()
+
+	r, err := NewResolver(client, registry, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.r = r
+	return r
+}
+
+var (
+	registry        = common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+	resolverAddress = common.HexToAddress("0x4444444444444444444444444444444444444444")
+)
+
+// fakeClient answers like a registry pointing every name to a single resolver.
+type fakeClient struct {
+	r       *resolver
+	name    string
+	address common.Address
+	err     error
+	calls   int
+}
+
+func (c *fakeClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	if *msg.To == registry {
+		return c.r.registryABI.Methods["resolver"].Outputs.Pack(resolverAddress)
+	}
+
+	if bytes.HasPrefix(msg.Data, c.r.resolverABI.Methods["name"].ID) {
+		return c.r.resolverABI.Methods["name"].Outputs.Pack(c.name)
+	}
+	return c.r.resolverABI.Methods["addr"].Outputs.Pack(c.address)
+}
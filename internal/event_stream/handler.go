@@ -0,0 +1,153 @@
+package eventstream
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+)
+
+func NewHandler(authenticator authenticator, storage streamStorage, pollInterval time.Duration) *handler {
+	return &handler{
+		authenticator: authenticator,
+		storage:       storage,
+		pollInterval:  pollInterval,
+	}
+}
+
+// ServeHTTP streams the transaction events of the user as Server-Sent Events. The token is taken
+// from the Authorization header ("Bearer <token>") or the token query parameter, as EventSource
+// cannot set headers. Streams resume after the Last-Event-ID header or the lastEventId query parameter,
+// without either they start with the next event.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token = strings.TrimPrefix(header, "Bearer ")
+	}
+
+	username, err := h.authenticator.Username(token)
+	if err != nil {
+		http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	lastEventID, err := h.lastEventID(r, username)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", retryDelay.Milliseconds())
+	flusher.Flush()
+
+	if err := h.stream(r.Context(), w, flusher, token, username, lastEventID); err != nil {
+		log.Println(fmt.Errorf("event stream of (%s): %s", username, err))
+	}
+}
+
+// stream polls the journal, so events recorded while the client was away and live ones
+// are delivered the same way and in order.
+func (h *handler) stream(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, token, username string, lastEventID int64) error {
+	poll := time.NewTicker(h.pollInterval)
+	defer poll.Stop()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-keepAlive.C:
+			if _, err := h.authenticator.Username(token); err != nil {
+				fmt.Fprint(w, "event: error\ndata: token expired\n\n")
+				flusher.Flush()
+				return nil
+			}
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return err
+			}
+			flusher.Flush()
+		case <-poll.C:
+			for {
+				events, err := h.storage.GetEventsAfter(ctx, username, lastEventID, batchSize)
+				if err != nil {
+					return err
+				}
+
+				for _, event := range events {
+					if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.EventType, event.Payload); err != nil {
+						return err
+					}
+					lastEventID = event.ID
+				}
+				flusher.Flush()
+
+				if len(events) < batchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+func (h *handler) lastEventID(r *http.Request, username string) (int64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("lastEventId")
+	}
+
+	if value == "" {
+		return h.storage.GetLastEventID(r.Context(), username)
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid last event id (%s)", value)
+	}
+
+	return id, nil
+}
+
+const (
+	batchSize         = 100
+	keepAliveInterval = 15 * time.Second
+	retryDelay        = 3 * time.Second
+)
+
+type authenticator interface {
+	Username(token string) (string, error)
+}
+
+type streamStorage interface {
+	GetEventsAfter(ctx context.Context, username string, afterID int64, limit int) ([]model.StoredEvent, error)
+	GetLastEventID(ctx context.Context, username string) (int64, error)
+}
+
+type handler struct {
+	authenticator authenticator
+	storage       streamStorage
+	pollInterval  time.Duration
+}
@@ -0,0 +1,74 @@
+package eventstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+)
+
+func NewJournal(storage journalStorage, retention time.Duration) *journal {
+	return &journal{
+		storage:   storage,
+		retention: retention,
+	}
+}
+
+// Run records the transaction events of the users, so streams can be resumed from
+// any event that is younger than the retention.
+func (j *journal) Run(ctx context.Context, events <-chan model.Event) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := j.storage.PruneEvents(ctx, time.Now().Add(-j.retention).UnixNano()); err != nil {
+				log.Println(fmt.Errorf("failed to prune tx events: %s", err))
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if event.Username == "" || event.Transaction == nil {
+				continue
+			}
+			if err := j.record(ctx, event); err != nil {
+				log.Println(fmt.Errorf("failed to record %s event of tx (%s): %s", event.Type, event.Transaction.TransactionHash, err))
+			}
+		}
+	}
+}
+
+func (j *journal) record(ctx context.Context, event model.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return j.storage.StoreEvent(ctx, model.StoredEvent{
+		Username:        event.Username,
+		ChainID:         event.ChainID,
+		TransactionHash: event.Transaction.TransactionHash,
+		EventType:       event.Type,
+		Payload:         string(payload),
+		CreatedAt:       event.CreatedAt,
+	})
+}
+
+const pruneInterval = time.Hour
+
+type journalStorage interface {
+	StoreEvent(ctx context.Context, event model.StoredEvent) error
+	PruneEvents(ctx context.Context, before int64) error
+}
+
+type journal struct {
+	storage   journalStorage
+	retention time.Duration
+}
@@ -0,0 +1,91 @@
+package events
+
+import (
+	"log"
+	"sync"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+)
+
+func NewBus() *bus {
+	return &bus{subscribers: make(map[int]*subscriber)}
+}
+
+// Publish waits for blocking subscribers and never waits for the others. Subscribers that are
+// not blocking miss events when they fall behind.
+func (b *bus) Publish(event model.Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for id, s := range b.subscribers {
+		if s.blocking {
+			select {
+			case s.events <- event:
+			case <-s.done:
+			}
+			continue
+		}
+
+		select {
+		case s.events <- event:
+		default:
+			log.Printf("event bus subscriber (%d) is full, dropping %s event", id, event.Type)
+		}
+	}
+}
+
+// Subscribe is meant for best-effort consumers such as connected clients.
+func (b *bus) Subscribe() (<-chan model.Event, func()) {
+	return b.subscribe(false)
+}
+
+// SubscribeBlocking is meant for consumers that persist events. Publishers wait for them, so
+// they must keep reading until they unsubscribe.
+func (b *bus) SubscribeBlocking() (<-chan model.Event, func()) {
+	return b.subscribe(true)
+}
+
+func (b *bus) subscribe(blocking bool) (<-chan model.Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+
+	s := &subscriber{
+		events:   make(chan model.Event, subscriberBuffer),
+		done:     make(chan struct{}),
+		blocking: blocking,
+	}
+	b.subscribers[id] = s
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			// Releases a publisher waiting for the subscriber before taking the lock it holds
+			close(s.done)
+
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			delete(b.subscribers, id)
+			close(s.events)
+		})
+	}
+
+	return s.events, unsubscribe
+}
+
+const subscriberBuffer = 1024
+
+type subscriber struct {
+	events   chan model.Event
+	done     chan struct{}
+	blocking bool
+}
+
+type bus struct {
+	mu          sync.RWMutex
+	nextID      int
+	subscribers map[int]*subscriber
+}
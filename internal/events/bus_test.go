@@ -0,0 +1,67 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+)
+
+func TestBlockingSubscribersReceiveEveryEvent(t *testing.T) {
+	b := NewBus()
+	blocking, _ := b.SubscribeBlocking()
+	lossy, _ := b.Subscribe()
+
+	const count = subscriberBuffer * 3
+	published := make(chan struct{})
+	go func() {
+		for i := 0; i < count; i++ {
+			b.Publish(model.Event{Type: model.NewBlockEvent, CreatedAt: int64(i)})
+		}
+		close(published)
+	}()
+
+	for i := 0; i < count; i++ {
+		select {
+		case event := <-blocking:
+			if event.CreatedAt != int64(i) {
+				t.Fatalf("event %d arrived as %d", i, event.CreatedAt)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d of %d events", i, count)
+		}
+	}
+	<-published
+
+	if len(lossy) != subscriberBuffer {
+		t.Fatalf("best-effort subscriber has %d events, want a full buffer of %d", len(lossy), subscriberBuffer)
+	}
+}
+
+func TestUnsubscribeReleasesWaitingPublisher(t *testing.T) {
+	b := NewBus()
+	_, unsubscribe := b.SubscribeBlocking()
+
+	published := make(chan struct{})
+	go func() {
+		for i := 0; i <= subscriberBuffer; i++ {
+			b.Publish(model.Event{Type: model.NewBlockEvent})
+		}
+		close(published)
+	}()
+
+	select {
+	case <-published:
+		t.Fatal("publisher did not wait for the full subscriber")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unsubscribe()
+	unsubscribe()
+
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("publisher still waits after unsubscribe")
+	}
+}
@@ -0,0 +1,112 @@
+package exporter
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ERC-20 and ERC-721 share the event, the indexed token ID of ERC-721 takes the place of the amount.
+func decodeTransfer(txLog *types.Log) (transfer, bool) {
+	if len(txLog.Topics) == 0 || txLog.Topics[0] != transferTopic {
+		return transfer{}, false
+	}
+
+	switch {
+	case len(txLog.Topics) == 3 && len(txLog.Data) == 32:
+		return transfer{
+			standard: "erc20",
+			from:     common.BytesToAddress(txLog.Topics[1].Bytes()).Hex(),
+			to:       common.BytesToAddress(txLog.Topics[2].Bytes()).Hex(),
+			value:    new(big.Int).SetBytes(txLog.Data).String(),
+		}, true
+	case len(txLog.Topics) == 4 && len(txLog.Data) == 0:
+		return transfer{
+			standard: "erc721",
+			from:     common.BytesToAddress(txLog.Topics[1].Bytes()).Hex(),
+			to:       common.BytesToAddress(txLog.Topics[2].Bytes()).Hex(),
+			value:    txLog.Topics[3].Big().String(),
+		}, true
+	}
+
+	return transfer{}, false
+}
+
+func topic(index int) func(r record) interface{} {
+	return func(r record) interface{} {
+		if index < len(r.log.Topics) {
+			return r.log.Topics[index].Hex()
+		}
+		return nil
+	}
+}
+
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+var (
+	chainIDColumn     = column{"chainId", func(r record) interface{} { return r.tx.ChainID }}
+	txHashColumn      = column{"transactionHash", func(r record) interface{} { return r.tx.TransactionHash }}
+	blockNumberColumn = column{"blockNumber", func(r record) interface{} { return r.tx.BlockNumber }}
+	logIndexColumn    = column{"logIndex", func(r record) interface{} { return r.log.Index }}
+)
+
+var datasetColumns = map[Dataset][]column{
+	TransactionsDataset: {
+		chainIDColumn,
+		txHashColumn,
+		{"status", func(r record) interface{} { return r.tx.TransactionStatus.String() }},
+		{"blockHash", func(r record) interface{} { return r.tx.BlockHash }},
+		blockNumberColumn,
+		{"transactionIndex", func(r record) interface{} { return r.tx.TransactionIndex }},
+		{"from", func(r record) interface{} { return r.tx.From }},
+		{"to", func(r record) interface{} { return r.tx.To }},
+		{"contractAddress", func(r record) interface{} { return r.tx.ContractAddress }},
+		{"nonce", func(r record) interface{} { return r.tx.Nonce }},
+		{"value", func(r record) interface{} { return r.tx.Value }},
+		{"input", func(r record) interface{} { return r.tx.Input }},
+		{"logsCount", func(r record) interface{} { return r.tx.LogsCount }},
+		{"replacedBy", func(r record) interface{} { return r.tx.ReplacedBy }},
+		{"firstSeenAt", func(r record) interface{} { return r.tx.FirstSeenAt }},
+		{"verified", func(r record) interface{} { return r.tx.Verified }},
+		{"receiptVerified", func(r record) interface{} { return r.tx.ReceiptVerified }},
+		{"finalized", func(r record) interface{} { return r.tx.Finalized }},
+	},
+	LogsDataset: {
+		chainIDColumn,
+		txHashColumn,
+		blockNumberColumn,
+		logIndexColumn,
+		{"address", func(r record) interface{} { return r.log.Address.Hex() }},
+		{"topic0", topic(0)},
+		{"topic1", topic(1)},
+		{"topic2", topic(2)},
+		{"topic3", topic(3)},
+		{"data", func(r record) interface{} { return hexutil.Encode(r.log.Data) }},
+	},
+	TransfersDataset: {
+		chainIDColumn,
+		txHashColumn,
+		blockNumberColumn,
+		logIndexColumn,
+		{"token", func(r record) interface{} { return r.log.Address.Hex() }},
+		{"standard", func(r record) interface{} { return r.transfer.standard }},
+		{"from", func(r record) interface{} { return r.transfer.from }},
+		{"to", func(r record) interface{} { return r.transfer.to }},
+		{"value", func(r record) interface{} { return r.transfer.value }},
+	},
+}
+
+type transfer struct {
+	standard string
+	from     string
+	to       string
+	value    string
+}
+
+type column struct {
+	name  string
+	value func(r record) interface{}
+}
@@ -0,0 +1,280 @@
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/avalkov/eth-node-interaction/internal/chains"
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func NewExporter(storage storage, chains chainRegistry) *exporter {
+	return &exporter{
+		storage: storage,
+		chains:  chains,
+	}
+}
+
+// Export writes the records of the dataset as they are read. Logs and token transfers are taken
+// from the receipts of the matching cached transactions, so they need the node of the chain.
+func (e *exporter) Export(ctx context.Context, w io.Writer, request Request) error {
+	columns, err := selectColumns(request.Dataset, request.Columns)
+	if err != nil {
+		return err
+	}
+
+	chainID, err := e.chains.ChainID(request.Chain)
+	if err != nil {
+		return err
+	}
+
+	writer, err := newRecordWriter(w, request.Format, columns)
+	if err != nil {
+		return err
+	}
+
+	filter := request.Filter
+	filter.ChainID = chainID
+
+	client, err := e.client(request)
+	if err != nil {
+		return err
+	}
+
+	if err := e.storage.StreamTxs(ctx, filter, func(tx model.Transaction) error {
+		if request.Dataset == TransactionsDataset {
+			return writer.write(record{tx: &tx})
+		}
+
+		if tx.BlockHash == nil || tx.TransactionStatus != model.Successful {
+			return nil
+		}
+
+		receipt, err := client.TransactionReceipt(ctx, common.HexToHash(tx.TransactionHash))
+		if err != nil {
+			return fmt.Errorf("failed to get receipt of tx (%s): %s", tx.TransactionHash, err)
+		}
+
+		for _, txLog := range receipt.Logs {
+			if request.Dataset == LogsDataset {
+				if err := writer.write(record{tx: &tx, log: txLog}); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if transfer, ok := decodeTransfer(txLog); ok {
+				if err := writer.write(record{tx: &tx, log: txLog, transfer: &transfer}); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return writer.flush()
+}
+
+func (e *exporter) client(request Request) (client, error) {
+	if request.Dataset == TransactionsDataset {
+		return nil, nil
+	}
+
+	chain, err := e.chains.Get(request.Chain)
+	if err != nil {
+		return nil, err
+	}
+
+	return chain.Client, nil
+}
+
+func selectColumns(dataset Dataset, names []string) ([]column, error) {
+	available, ok := datasetColumns[dataset]
+	if !ok {
+		return nil, fmt.Errorf("unknown dataset (%s)", dataset)
+	}
+
+	if len(names) == 0 {
+		return available, nil
+	}
+
+	selected := []column{}
+	for _, name := range names {
+		found := false
+		for _, c := range available {
+			if c.name == name {
+				selected = append(selected, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column (%s) of dataset (%s)", name, dataset)
+		}
+	}
+
+	return selected, nil
+}
+
+func newRecordWriter(w io.Writer, format Format, columns []column) (*recordWriter, error) {
+	buffered := bufio.NewWriter(w)
+	rw := &recordWriter{columns: columns, buffered: buffered}
+
+	switch format {
+	case CsvFormat:
+		rw.csv = csv.NewWriter(buffered)
+		header := []string{}
+		for _, c := range columns {
+			header = append(header, c.name)
+		}
+		if err := rw.csv.Write(header); err != nil {
+			return nil, err
+		}
+	case NdjsonFormat:
+	default:
+		return nil, fmt.Errorf("unknown format (%s)", format)
+	}
+
+	return rw, nil
+}
+
+func (rw *recordWriter) write(r record) error {
+	if rw.csv != nil {
+		values := make([]string, len(rw.columns))
+		for i, c := range rw.columns {
+			values[i] = csvValue(c.value(r))
+		}
+		return rw.csv.Write(values)
+	}
+
+	// Keys keep the column order
+	var line strings.Builder
+	line.WriteString("{")
+	for i, c := range rw.columns {
+		if i > 0 {
+			line.WriteString(",")
+		}
+		key, _ := json.Marshal(c.name)
+		value, err := json.Marshal(c.value(r))
+		if err != nil {
+			return err
+		}
+		line.Write(key)
+		line.WriteString(":")
+		line.Write(value)
+	}
+	line.WriteString("}\n")
+
+	_, err := rw.buffered.WriteString(line.String())
+	return err
+}
+
+func (rw *recordWriter) flush() error {
+	if rw.csv != nil {
+		rw.csv.Flush()
+		if err := rw.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return rw.buffered.Flush()
+}
+
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case *string:
+		if v == nil {
+			return ""
+		}
+		return *v
+	case *uint64:
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(*v)
+	case *int:
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(*v)
+	case *int64:
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(*v)
+	case *uint:
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(*v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type Format string
+
+const (
+	CsvFormat    Format = "csv"
+	NdjsonFormat Format = "ndjson"
+)
+
+type Dataset string
+
+const (
+	TransactionsDataset Dataset = "transactions"
+	LogsDataset         Dataset = "logs"
+	TransfersDataset    Dataset = "transfers"
+)
+
+// Request selects the records and their columns. All columns of the dataset are written
+// when none are selected.
+type Request struct {
+	Format  Format
+	Dataset Dataset
+	Columns []string
+	Chain   string
+	Filter  model.TxFilter
+}
+
+type record struct {
+	tx       *model.Transaction
+	log      *types.Log
+	transfer *transfer
+}
+
+type recordWriter struct {
+	columns  []column
+	buffered *bufio.Writer
+	// nil for NDJSON
+	csv *csv.Writer
+}
+
+type storage interface {
+	StreamTxs(ctx context.Context, filter model.TxFilter, fn func(model.Transaction) error) error
+}
+
+type chainRegistry interface {
+	Get(selector string) (*chains.Chain, error)
+	ChainID(selector string) (uint64, error)
+}
+
+type client interface {
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+type exporter struct {
+	storage storage
+	chains  chainRegistry
+}
@@ -0,0 +1,75 @@
+package exporter
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+func NewHandler(authenticator authenticator, exporter *exporter) *handler {
+	return &handler{
+		authenticator: authenticator,
+		exporter:      exporter,
+	}
+}
+
+// ServeHTTP streams the export selected by the query parameters. The token is taken from the
+// Authorization header ("Bearer <token>") or the token query parameter.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token = strings.TrimPrefix(header, "Bearer ")
+	}
+
+	if err := h.authenticator.VerifyToken(token); err != nil {
+		http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+		return
+	}
+
+	request, err := RequestFromValues(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Invalid columns and chains are reported before the response starts
+	if _, err := selectColumns(request.Dataset, request.Columns); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := h.exporter.chains.ChainID(request.Chain); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch request.Format {
+	case CsvFormat:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	case NdjsonFormat:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	default:
+		http.Error(w, fmt.Sprintf("unknown format (%s)", request.Format), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s.%s", request.Dataset, request.Format)))
+
+	if err := h.exporter.Export(r.Context(), w, request); err != nil {
+		// The status is already sent, the truncated body is all the client gets
+		log.Println(fmt.Errorf("export of %s failed: %s", request.Dataset, err))
+	}
+}
+
+type authenticator interface {
+	VerifyToken(token string) error
+}
+
+type handler struct {
+	authenticator authenticator
+	exporter      *exporter
+}
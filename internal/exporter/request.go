@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// RequestFromValues reads the export parameters shared by the subcommand and the endpoint:
+// format (csv), dataset (transactions), columns, chain, fromBlock, toBlock, address and status.
+func RequestFromValues(values url.Values) (Request, error) {
+	request := Request{
+		Format:  Format(valueOr(values, "format", string(CsvFormat))),
+		Dataset: Dataset(valueOr(values, "dataset", string(TransactionsDataset))),
+		Chain:   values.Get("chain"),
+	}
+
+	for _, name := range strings.Split(values.Get("columns"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			request.Columns = append(request.Columns, name)
+		}
+	}
+
+	var err error
+	if request.Filter.FromBlock, err = blockValue(values, "fromBlock"); err != nil {
+		return Request{}, err
+	}
+	if request.Filter.ToBlock, err = blockValue(values, "toBlock"); err != nil {
+		return Request{}, err
+	}
+
+	if address := values.Get("address"); address != "" {
+		if !common.IsHexAddress(address) {
+			return Request{}, fmt.Errorf("invalid address (%s)", address)
+		}
+		normalized := common.HexToAddress(address).Hex()
+		request.Filter.Address = &normalized
+	}
+
+	if status := values.Get("status"); status != "" {
+		parsed, err := model.ParseTxStatus(status)
+		if err != nil {
+			return Request{}, err
+		}
+		request.Filter.Status = &parsed
+	}
+
+	return request, nil
+}
+
+func blockValue(values url.Values, key string) (*uint64, error) {
+	value := values.Get(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s (%s)", key, value)
+	}
+
+	return &number, nil
+}
+
+func valueOr(values url.Values, key, defaultValue string) string {
+	if value := values.Get(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,189 @@
+package gasoracle
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func NewGasOracle(client client) *gasOracle {
+	return &gasOracle{client: client}
+}
+
+func (o *gasOracle) FeeSuggestions(ctx context.Context, txHash *string) (model.FeeSuggestions, error) {
+	suggestions, nextBaseFee, priorityFees, err := o.latest(ctx)
+	if err != nil {
+		return model.FeeSuggestions{}, err
+	}
+
+	if txHash != nil {
+		comparison, err := o.compare(ctx, common.HexToHash(*txHash), nextBaseFee, priorityFees)
+		if err != nil {
+			return model.FeeSuggestions{}, err
+		}
+		suggestions.TxComparison = &comparison
+	}
+
+	return suggestions, nil
+}
+
+// The suggestions only change with a new block, so they are cached until one arrives.
+func (o *gasOracle) latest(ctx context.Context) (model.FeeSuggestions, *big.Int, [3]*big.Int, error) {
+	blockNumber, err := o.client.BlockNumber(ctx)
+	if err != nil {
+		return model.FeeSuggestions{}, nil, [3]*big.Int{}, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.cached != nil && o.cached.BlockNumber == blockNumber {
+		return *o.cached, o.cachedBaseFee, o.cachedPriorityFees, nil
+	}
+
+	history, err := o.client.FeeHistory(ctx, historyBlocks, new(big.Int).SetUint64(blockNumber), rewardPercentiles)
+	if err != nil {
+		return model.FeeSuggestions{}, nil, [3]*big.Int{}, fmt.Errorf("failed to get fee history: %s", err)
+	}
+
+	if len(history.BaseFee) == 0 {
+		return model.FeeSuggestions{}, nil, [3]*big.Int{}, fmt.Errorf("node returned empty fee history")
+	}
+
+	// The last base fee of the history is the one of the next block
+	nextBaseFee := history.BaseFee[len(history.BaseFee)-1]
+
+	var priorityFees [3]*big.Int
+	for i := range rewardPercentiles {
+		priorityFees[i] = medianReward(history.Reward, i)
+	}
+
+	suggestions := model.FeeSuggestions{
+		BlockNumber: blockNumber,
+		NextBaseFee: nextBaseFee.String(),
+		PriorityFees: model.PriorityFees{
+			Slow:     priorityFees[0].String(),
+			Standard: priorityFees[1].String(),
+			Fast:     priorityFees[2].String(),
+		},
+		MaxFees: model.PriorityFees{
+			Slow:     maxFee(nextBaseFee, priorityFees[0]).String(),
+			Standard: maxFee(nextBaseFee, priorityFees[1]).String(),
+			Fast:     maxFee(nextBaseFee, priorityFees[2]).String(),
+		},
+	}
+
+	// The next block is the first one, its base fee is already known
+	for _, blocks := range projectedBlocks {
+		suggestions.BaseFeeMaxIn = append(suggestions.BaseFeeMaxIn, model.BaseFeeBound{
+			Blocks:  blocks,
+			BaseFee: projectBaseFee(nextBaseFee, blocks-1).String(),
+		})
+	}
+
+	o.cached = &suggestions
+	o.cachedBaseFee = nextBaseFee
+	o.cachedPriorityFees = priorityFees
+
+	return suggestions, nextBaseFee, priorityFees, nil
+}
+
+func (o *gasOracle) compare(ctx context.Context, hash common.Hash, nextBaseFee *big.Int, priorityFees [3]*big.Int) (model.FeeComparison, error) {
+	tx, isPending, err := o.client.TransactionByHash(ctx, hash)
+	if err != nil {
+		return model.FeeComparison{}, fmt.Errorf("failed to get tx (%s): %s", hash.Hex(), err)
+	}
+
+	comparison := model.FeeComparison{
+		TransactionHash: hash.Hex(),
+		MaxFee:          tx.GasFeeCap().String(),
+		MaxPriorityFee:  tx.GasTipCap().String(),
+	}
+
+	effectiveTip, tipErr := tx.EffectiveGasTip(nextBaseFee)
+	if tipErr != nil {
+		effectiveTip = big.NewInt(0)
+	}
+	comparison.EffectiveTip = effectiveTip.String()
+
+	switch {
+	case !isPending:
+		comparison.Tier = "mined"
+	case tipErr != nil:
+		comparison.Tier = "underpriced"
+	case effectiveTip.Cmp(priorityFees[2]) >= 0:
+		comparison.Tier = "fast"
+	case effectiveTip.Cmp(priorityFees[1]) >= 0:
+		comparison.Tier = "standard"
+	case effectiveTip.Cmp(priorityFees[0]) >= 0:
+		comparison.Tier = "slow"
+	default:
+		comparison.Tier = "underpriced"
+	}
+
+	return comparison, nil
+}
+
+// Empty blocks report zero rewards and would drag the median down, so they are skipped.
+func medianReward(rewards [][]*big.Int, percentile int) *big.Int {
+	values := []*big.Int{}
+	for _, blockRewards := range rewards {
+		if percentile < len(blockRewards) && blockRewards[percentile].Sign() > 0 {
+			values = append(values, blockRewards[percentile])
+		}
+	}
+
+	if len(values) == 0 {
+		return big.NewInt(0)
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Cmp(values[j]) < 0
+	})
+
+	return values[len(values)/2]
+}
+
+// Leaves room for the base fee to double before the transaction becomes unminable.
+func maxFee(baseFee, priorityFee *big.Int) *big.Int {
+	fee := new(big.Int).Mul(baseFee, big.NewInt(2))
+	return fee.Add(fee, priorityFee)
+}
+
+// The base fee grows by at most 12.5% per block, rounded down like the protocol does.
+func projectBaseFee(baseFee *big.Int, blocks uint64) *big.Int {
+	projected := new(big.Int).Set(baseFee)
+	for i := uint64(0); i < blocks; i++ {
+		projected.Mul(projected, big.NewInt(9))
+		projected.Div(projected, big.NewInt(8))
+	}
+	return projected
+}
+
+const historyBlocks = 20
+
+var rewardPercentiles = []float64{10, 50, 90}
+
+var projectedBlocks = []uint64{1, 3, 6, 12}
+
+type client interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error)
+	TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error)
+}
+
+type gasOracle struct {
+	client client
+
+	mu                 sync.Mutex
+	cached             *model.FeeSuggestions
+	cachedBaseFee      *big.Int
+	cachedPriorityFees [3]*big.Int
+}
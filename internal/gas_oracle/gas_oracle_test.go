@@ -0,0 +1,72 @@
+package gasoracle
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestProjectBaseFee(t *testing.T) {
+	tests := []struct {
+		baseFee int64
+		blocks  uint64
+		want    int64
+	}{
+		{1000, 0, 1000},
+		{1000, 1, 1125},
+		{1000, 2, 1265},
+		{1000, 3, 1423},
+		{7, 1, 7},
+		{8, 1, 9},
+		{0, 5, 0},
+	}
+
+	for _, test := range tests {
+		if got := projectBaseFee(big.NewInt(test.baseFee), test.blocks); got.Int64() != test.want {
+			t.Errorf("projectBaseFee(%d, %d) = %s, want %d", test.baseFee, test.blocks, got, test.want)
+		}
+	}
+}
+
+func TestBaseFeeBoundsStartAtTheNextBlock(t *testing.T) {
+	o := NewGasOracle(fakeClient{baseFees: []int64{900, 1000}})
+
+	suggestions, err := o.FeeSuggestions(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[uint64]string{1: "1000", 3: "1265", 6: "1800", 12: "3647"}
+	if len(suggestions.BaseFeeMaxIn) != len(want) {
+		t.Fatalf("%d bounds, want %d", len(suggestions.BaseFeeMaxIn), len(want))
+	}
+	for _, bound := range suggestions.BaseFeeMaxIn {
+		if bound.BaseFee != want[bound.Blocks] {
+			t.Errorf("bound in %d blocks = %s, want %s", bound.Blocks, bound.BaseFee, want[bound.Blocks])
+		}
+	}
+}
+
+type fakeClient struct {
+	baseFees []int64
+}
+
+func (c fakeClient) BlockNumber(ctx context.Context) (uint64, error) {
+	return 100, nil
+}
+
+func (c fakeClient) FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error) {
+	history := &ethereum.FeeHistory{OldestBlock: big.NewInt(99)}
+	for _, baseFee := range c.baseFees {
+		history.BaseFee = append(history.BaseFee, big.NewInt(baseFee))
+	}
+	return history, nil
+}
+
+func (c fakeClient) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	return nil, false, ethereum.NotFound
+}
@@ -0,0 +1,118 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/avalkov/eth-node-interaction/internal/chains"
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	rpcservices "github.com/avalkov/eth-node-interaction/internal/rpc_services"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/graph-gophers/graphql-go"
+)
+
+func NewHandler(authenticator authenticator, storage storage, chains chainRegistry, backends map[uint64]rpcservices.ChainBackend) *handler {
+	return &handler{
+		authenticator: authenticator,
+		schema: graphql.MustParseSchema(schema, &resolver{
+			storage:  storage,
+			chains:   chains,
+			backends: backends,
+		}, graphql.MaxDepth(maxQueryDepth), graphql.MaxParallelism(maxParallelism)),
+	}
+}
+
+// ServeHTTP executes a query sent as a JSON body with POST or as query parameters with GET. The token
+// is optional and taken from the Authorization header ("Bearer <token>") or the token query parameter.
+// Queries are limited in length and depth, and in the node requests they need.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		params.Query = r.URL.Query().Get("query")
+		params.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &params.Variables); err != nil {
+				http.Error(w, "invalid variables", http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if len(params.Query) > maxQueryLength {
+		http.Error(w, "query is too long", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token = strings.TrimPrefix(header, "Bearer ")
+	}
+
+	ctx := context.WithValue(r.Context(), loaderKey{}, newLoader(maxRequestLoads))
+	if token != "" {
+		username, err := h.authenticator.Username(token)
+		if err != nil {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		ctx = context.WithValue(ctx, ownerKey{}, &model.TxOwner{Token: token, Username: username})
+	}
+
+	response := h.schema.Exec(ctx, params.Query, params.OperationName, params.Variables)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(response)
+}
+
+func ownerFromContext(ctx context.Context) *model.TxOwner {
+	owner, _ := ctx.Value(ownerKey{}).(*model.TxOwner)
+	return owner
+}
+
+const (
+	maxQueryLength  = 10000
+	maxQueryDepth   = 8
+	maxParallelism  = 10
+	maxRequestLoads = 1000
+)
+
+type ownerKey struct{}
+
+type authenticator interface {
+	Username(token string) (string, error)
+}
+
+type storage interface {
+	GetTxs(ctx context.Context, chainID uint64, hashes []string) ([]model.Transaction, error)
+	AddTxOwner(ctx context.Context, chainID uint64, hash string, owner model.TxOwner) error
+	ListTxs(ctx context.Context, query model.TxQuery) ([]model.Transaction, error)
+}
+
+type chainRegistry interface {
+	Get(selector string) (*chains.Chain, error)
+}
+
+type client interface {
+	TransactionCount(ctx context.Context, blockHash common.Hash) (uint, error)
+}
+
+type handler struct {
+	authenticator authenticator
+	schema        *graphql.Schema
+}
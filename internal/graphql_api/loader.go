@@ -0,0 +1,229 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func newLoader(maxLoads int) *loader {
+	return &loader{
+		maxLoads: maxLoads,
+		headers:  make(map[loadKey]*headerResult),
+		receipts: make(map[loadKey]*receiptResult),
+	}
+}
+
+// queue registers the blocks and receipts of the transactions of a list. They are fetched in
+// one batch together with the first of them that is selected.
+func (l *loader) queue(c *chain, transactions []model.Transaction) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, tx := range transactions {
+		if tx.BlockHash == nil {
+			continue
+		}
+		l.queuedHeaders = append(l.queuedHeaders, loadKey{c.id, common.HexToHash(*tx.BlockHash)})
+		l.queuedReceipts = append(l.queuedReceipts, loadKey{c.id, common.HexToHash(tx.TransactionHash)})
+	}
+}
+
+// charge counts the node requests of the query and fails once there are more than maxLoads.
+func (l *loader) charge(count int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.chargeLocked(count)
+}
+
+func (l *loader) header(ctx context.Context, c *chain, hash common.Hash) (*types.Header, common.Hash, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := loadKey{c.id, hash}
+	if _, ok := l.headers[key]; !ok {
+		keys := l.pending(c.id, append(l.queuedHeaders, key), func(key loadKey) bool {
+			_, ok := l.headers[key]
+			return ok
+		})
+		if err := l.chargeLocked(len(keys)); err != nil {
+			return nil, common.Hash{}, err
+		}
+
+		raw := make([]json.RawMessage, len(keys))
+		elems := make([]rpc.BatchElem, len(keys))
+		for i, key := range keys {
+			elems[i] = rpc.BatchElem{Method: "eth_getBlockByHash", Args: []interface{}{key.hash, false}, Result: &raw[i]}
+		}
+		err := batchCall(ctx, c.rpc, elems)
+
+		for i, key := range keys {
+			result := &headerResult{err: err}
+			if err == nil {
+				if result.err = elems[i].Error; result.err == nil {
+					result.header, result.hash, result.err = decodeHeader(raw[i])
+				}
+			}
+			l.headers[key] = result
+		}
+	}
+
+	result := l.headers[key]
+	return result.header, result.hash, result.err
+}
+
+// headerByNumber is not batched, blocks are only loaded by number at the top of a query.
+func (l *loader) headerByNumber(ctx context.Context, c *chain, blockParam string) (*types.Header, common.Hash, error) {
+	if err := l.charge(1); err != nil {
+		return nil, common.Hash{}, err
+	}
+
+	var raw json.RawMessage
+	if err := c.rpc.CallContext(ctx, &raw, "eth_getBlockByNumber", blockParam, false); err != nil {
+		return nil, common.Hash{}, err
+	}
+
+	return decodeHeader(raw)
+}
+
+func (l *loader) receipt(ctx context.Context, c *chain, hash common.Hash) (*types.Receipt, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := loadKey{c.id, hash}
+	if _, ok := l.receipts[key]; !ok {
+		keys := l.pending(c.id, append(l.queuedReceipts, key), func(key loadKey) bool {
+			_, ok := l.receipts[key]
+			return ok
+		})
+		if err := l.chargeLocked(len(keys)); err != nil {
+			return nil, err
+		}
+
+		receipts := make([]*types.Receipt, len(keys))
+		elems := make([]rpc.BatchElem, len(keys))
+		for i, key := range keys {
+			elems[i] = rpc.BatchElem{Method: "eth_getTransactionReceipt", Args: []interface{}{key.hash}, Result: &receipts[i]}
+		}
+		err := batchCall(ctx, c.rpc, elems)
+
+		for i, key := range keys {
+			result := &receiptResult{receipt: receipts[i], err: err}
+			if err == nil {
+				result.err = elems[i].Error
+				if result.err == nil && receipts[i] == nil {
+					result.err = ethereum.NotFound
+				}
+			}
+			l.receipts[key] = result
+		}
+	}
+
+	result := l.receipts[key]
+	return result.receipt, result.err
+}
+
+// pending returns the distinct keys of the chain that are not loaded yet. Queued keys of other
+// chains are kept for later.
+func (l *loader) pending(chainID uint64, keys []loadKey, loaded func(loadKey) bool) []loadKey {
+	pending := []loadKey{}
+	seen := make(map[loadKey]struct{})
+	for _, key := range keys {
+		if _, ok := seen[key]; ok || key.chainID != chainID || loaded(key) {
+			continue
+		}
+		seen[key] = struct{}{}
+		pending = append(pending, key)
+	}
+	return pending
+}
+
+func (l *loader) chargeLocked(count int) error {
+	if l.loads+count > l.maxLoads {
+		return fmt.Errorf("query needs more than %d node requests", l.maxLoads)
+	}
+	l.loads += count
+	return nil
+}
+
+func batchCall(ctx context.Context, client rpcClient, elems []rpc.BatchElem) error {
+	for start := 0; start < len(elems); start += maxBatchSize {
+		end := min(start+maxBatchSize, len(elems))
+		if err := client.BatchCallContext(ctx, elems[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeHeader returns the header with the hash reported by the node, headers of forks that are
+// not known yet would hash differently locally.
+func decodeHeader(raw json.RawMessage) (*types.Header, common.Hash, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, common.Hash{}, ethereum.NotFound
+	}
+
+	var header types.Header
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return nil, common.Hash{}, err
+	}
+
+	var block struct {
+		Hash common.Hash `json:"hash"`
+	}
+	if err := json.Unmarshal(raw, &block); err != nil {
+		return nil, common.Hash{}, err
+	}
+
+	return &header, block.Hash, nil
+}
+
+func loaderFromContext(ctx context.Context) *loader {
+	if l, ok := ctx.Value(loaderKey{}).(*loader); ok {
+		return l
+	}
+	return newLoader(maxRequestLoads)
+}
+
+const maxBatchSize = 100
+
+type loaderKey struct{}
+
+type rpcClient interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+	BatchCallContext(ctx context.Context, b []rpc.BatchElem) error
+}
+
+type loadKey struct {
+	chainID uint64
+	hash    common.Hash
+}
+
+type headerResult struct {
+	header *types.Header
+	hash   common.Hash
+	err    error
+}
+
+type receiptResult struct {
+	receipt *types.Receipt
+	err     error
+}
+
+// loader is created for every request, so results are only shared within one query.
+type loader struct {
+	mu             sync.Mutex
+	maxLoads       int
+	loads          int
+	headers        map[loadKey]*headerResult
+	receipts       map[loadKey]*receiptResult
+	queuedHeaders  []loadKey
+	queuedReceipts []loadKey
+}
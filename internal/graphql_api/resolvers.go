@@ -0,0 +1,437 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	rpcservices "github.com/avalkov/eth-node-interaction/internal/rpc_services"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func (r *resolver) Transaction(ctx context.Context, args struct {
+	Hash  string
+	Chain *string
+}) (*transactionResolver, error) {
+	transactions, err := r.Transactions(ctx, struct {
+		Hashes []string
+		Chain  *string
+	}{[]string{args.Hash}, args.Chain})
+	if err != nil || len(transactions) == 0 {
+		return nil, err
+	}
+	return transactions[0], nil
+}
+
+func (r *resolver) Transactions(ctx context.Context, args struct {
+	Hashes []string
+	Chain  *string
+}) ([]*transactionResolver, error) {
+	c, err := r.chain(args.Chain)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := []string{}
+	seen := make(map[string]struct{})
+	for _, hash := range args.Hashes {
+		if len(hash) != 2+2*common.HashLength || !strings.HasPrefix(hash, "0x") {
+			return nil, fmt.Errorf("invalid hash (%s)", hash)
+		}
+		hash = common.HexToHash(hash).Hex()
+		if _, ok := seen[hash]; !ok {
+			seen[hash] = struct{}{}
+			hashes = append(hashes, hash)
+		}
+	}
+
+	owner := ownerFromContext(ctx)
+
+	cached, err := r.storage.GetTxs(ctx, c.id, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]model.Transaction)
+	for _, tx := range cached {
+		// Pending and dropped transactions may have changed since they were cached
+		if tx.TransactionStatus == model.Pending || tx.TransactionStatus == model.Dropped {
+			continue
+		}
+		if owner != nil {
+			if err := r.storage.AddTxOwner(ctx, c.id, tx.TransactionHash, *owner); err != nil {
+				return nil, err
+			}
+		}
+		found[tx.TransactionHash] = tx
+	}
+
+	missing := []string{}
+	for _, hash := range hashes {
+		if _, ok := found[hash]; !ok {
+			missing = append(missing, hash)
+		}
+	}
+
+	if len(missing) > 0 {
+		if err := loaderFromContext(ctx).charge(len(missing)); err != nil {
+			return nil, err
+		}
+		fetched, err := c.backend.TxFetcher.FetchTx(ctx, owner, missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, tx := range fetched {
+			found[tx.TransactionHash] = tx
+		}
+	}
+
+	ordered := []model.Transaction{}
+	for _, hash := range hashes {
+		if tx, ok := found[hash]; ok {
+			ordered = append(ordered, tx)
+		}
+	}
+
+	return newTransactionResolvers(ctx, c, ordered), nil
+}
+
+func (r *resolver) AllTransactions(ctx context.Context, args listArgs) (*connectionResolver, error) {
+	return r.list(ctx, nil, args)
+}
+
+func (r *resolver) MyTransactions(ctx context.Context, args listArgs) (*connectionResolver, error) {
+	owner := ownerFromContext(ctx)
+	if owner == nil {
+		return nil, errors.New("missing token")
+	}
+	return r.list(ctx, &owner.Token, args)
+}
+
+func (r *resolver) Block(ctx context.Context, args struct {
+	Number *Long
+	Hash   *string
+	Chain  *string
+}) (*blockResolver, error) {
+	c, err := r.chain(args.Chain)
+	if err != nil {
+		return nil, err
+	}
+
+	var header *types.Header
+	var hash common.Hash
+	switch {
+	case args.Hash != nil:
+		header, hash, err = loaderFromContext(ctx).header(ctx, c, common.HexToHash(*args.Hash))
+	case args.Number != nil:
+		if *args.Number < 0 {
+			return nil, fmt.Errorf("negative block number (%d)", *args.Number)
+		}
+		header, hash, err = loaderFromContext(ctx).headerByNumber(ctx, c, hexutil.EncodeUint64(uint64(*args.Number)))
+	default:
+		header, hash, err = loaderFromContext(ctx).headerByNumber(ctx, c, "latest")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &blockResolver{chain: c, header: header, hash: hash}, nil
+}
+
+func (r *resolver) list(ctx context.Context, token *string, args listArgs) (*connectionResolver, error) {
+	c, err := r.chain(args.Chain)
+	if err != nil {
+		return nil, err
+	}
+
+	request := rpcservices.ListTransactionsRequest{}
+	if args.First != nil {
+		request.Limit = int(*args.First)
+	}
+	if args.After != nil {
+		request.Cursor = *args.After
+	}
+	if args.Filter != nil {
+		request.Status = stringValue(args.Filter.Status)
+		request.From = stringValue(args.Filter.From)
+		request.To = stringValue(args.Filter.To)
+		request.ContractAddress = stringValue(args.Filter.ContractAddress)
+		request.HasLogs = args.Filter.HasLogs
+		request.Order = stringValue(args.Filter.Order)
+		if request.FromBlock, err = args.Filter.FromBlock.uint64Pointer(); err != nil {
+			return nil, err
+		}
+		if request.ToBlock, err = args.Filter.ToBlock.uint64Pointer(); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := c.backend.ListTransactions(ctx, token, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &connectionResolver{transactions: newTransactionResolvers(ctx, c, reply.Transactions), reply: reply}, nil
+}
+
+func (r *resolver) chain(selector *string) (*chain, error) {
+	resolved, err := r.chains.Get(stringValue(selector))
+	if err != nil {
+		return nil, err
+	}
+
+	backend, ok := r.backends[resolved.ID]
+	if !ok {
+		return nil, fmt.Errorf("chain (%d) is not served", resolved.ID)
+	}
+
+	return &chain{id: resolved.ID, backend: backend, client: resolved.Client, rpc: resolved.RPC, storage: r.storage}, nil
+}
+
+// newTransactionResolvers queues the blocks and receipts of the list, so selecting them loads
+// them in one batch instead of one node request per transaction.
+func newTransactionResolvers(ctx context.Context, c *chain, transactions []model.Transaction) []*transactionResolver {
+	loaderFromContext(ctx).queue(c, transactions)
+
+	resolvers := []*transactionResolver{}
+	for _, tx := range transactions {
+		resolvers = append(resolvers, &transactionResolver{chain: c, tx: tx})
+	}
+	return resolvers
+}
+
+func (cr *connectionResolver) Transactions() []*transactionResolver {
+	return cr.transactions
+}
+
+func (cr *connectionResolver) NextCursor() *string {
+	return cr.reply.NextCursor
+}
+
+func (tr *transactionResolver) ChainId() Long            { return Long(tr.tx.ChainID) }
+func (tr *transactionResolver) Hash() string             { return tr.tx.TransactionHash }
+func (tr *transactionResolver) Status() string           { return tr.tx.TransactionStatus.String() }
+func (tr *transactionResolver) BlockHash() *string       { return tr.tx.BlockHash }
+func (tr *transactionResolver) From() string             { return tr.tx.From }
+func (tr *transactionResolver) To() *string              { return tr.tx.To }
+func (tr *transactionResolver) ContractAddress() *string { return tr.tx.ContractAddress }
+func (tr *transactionResolver) Input() string            { return tr.tx.Input }
+func (tr *transactionResolver) Value() string            { return tr.tx.Value }
+func (tr *transactionResolver) ReplacedBy() *string      { return tr.tx.ReplacedBy }
+func (tr *transactionResolver) Verified() bool           { return tr.tx.Verified }
+func (tr *transactionResolver) ReceiptVerified() bool    { return tr.tx.ReceiptVerified }
+func (tr *transactionResolver) Finalized() bool          { return tr.tx.Finalized }
+func (tr *transactionResolver) BlockNumber() *Long       { return longPointer(tr.tx.BlockNumber) }
+func (tr *transactionResolver) Nonce() *Long             { return longPointer(tr.tx.Nonce) }
+func (tr *transactionResolver) TransactionIndex() *int32 { return int32Pointer(tr.tx.TransactionIndex) }
+
+func (tr *transactionResolver) LogsCount() *int32 {
+	if tr.tx.LogsCount == nil {
+		return nil
+	}
+	count := int32(*tr.tx.LogsCount)
+	return &count
+}
+
+func (tr *transactionResolver) FirstSeenAt() *Long {
+	if tr.tx.FirstSeenAt == nil {
+		return nil
+	}
+	firstSeenAt := Long(*tr.tx.FirstSeenAt)
+	return &firstSeenAt
+}
+
+// Names are only looked up when they are selected.
+func (tr *transactionResolver) FromName(ctx context.Context) (*string, error) {
+	if tr.tx.FromName != nil {
+		return tr.tx.FromName, nil
+	}
+	if err := loaderFromContext(ctx).charge(1); err != nil {
+		return nil, err
+	}
+	return tr.chain.backend.LookupName(ctx, &tr.tx.From), nil
+}
+
+func (tr *transactionResolver) ToName(ctx context.Context) (*string, error) {
+	if tr.tx.ToName != nil {
+		return tr.tx.ToName, nil
+	}
+	if err := loaderFromContext(ctx).charge(1); err != nil {
+		return nil, err
+	}
+	return tr.chain.backend.LookupName(ctx, tr.tx.To), nil
+}
+
+func (tr *transactionResolver) ContractAddressName(ctx context.Context) (*string, error) {
+	if tr.tx.ContractAddressName != nil {
+		return tr.tx.ContractAddressName, nil
+	}
+	if err := loaderFromContext(ctx).charge(1); err != nil {
+		return nil, err
+	}
+	return tr.chain.backend.LookupName(ctx, tr.tx.ContractAddress), nil
+}
+
+func (tr *transactionResolver) Block(ctx context.Context) (*blockResolver, error) {
+	if tr.tx.BlockHash == nil {
+		return nil, nil
+	}
+
+	header, hash, err := loaderFromContext(ctx).header(ctx, tr.chain, common.HexToHash(*tr.tx.BlockHash))
+	if err != nil {
+		return nil, err
+	}
+
+	return &blockResolver{chain: tr.chain, header: header, hash: hash}, nil
+}
+
+// Logs are read from the receipt, pending transactions have none.
+func (tr *transactionResolver) Logs(ctx context.Context) ([]*logResolver, error) {
+	logs := []*logResolver{}
+	if tr.tx.BlockHash == nil {
+		return logs, nil
+	}
+
+	receipt, err := loaderFromContext(ctx).receipt(ctx, tr.chain, common.HexToHash(tr.tx.TransactionHash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get receipt of tx (%s): %s", tr.tx.TransactionHash, err)
+	}
+
+	for _, txLog := range receipt.Logs {
+		logs = append(logs, &logResolver{transaction: tr, log: txLog})
+	}
+
+	return logs, nil
+}
+
+func (br *blockResolver) ChainId() Long      { return Long(br.chain.id) }
+func (br *blockResolver) Number() Long       { return Long(br.header.Number.Int64()) }
+func (br *blockResolver) Hash() string       { return br.hash.Hex() }
+func (br *blockResolver) ParentHash() string { return br.header.ParentHash.Hex() }
+func (br *blockResolver) Timestamp() Long    { return Long(br.header.Time) }
+
+func (br *blockResolver) TransactionsCount(ctx context.Context) (int32, error) {
+	if err := loaderFromContext(ctx).charge(1); err != nil {
+		return 0, err
+	}
+	count, err := br.chain.client.TransactionCount(ctx, br.hash)
+	return int32(count), err
+}
+
+func (br *blockResolver) Transactions(ctx context.Context) ([]*transactionResolver, error) {
+	number := br.header.Number.Uint64()
+
+	cached, err := br.chain.storage.ListTxs(ctx, model.TxQuery{
+		Filter: model.TxFilter{ChainID: br.chain.id, FromBlock: &number, ToBlock: &number},
+		Limit:  maxBlockTransactions,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := []model.Transaction{}
+	for _, tx := range cached {
+		if tx.BlockHash != nil && *tx.BlockHash == br.hash.Hex() {
+			transactions = append(transactions, tx)
+		}
+	}
+
+	return newTransactionResolvers(ctx, br.chain, transactions), nil
+}
+
+func (lr *logResolver) Index() int32                      { return int32(lr.log.Index) }
+func (lr *logResolver) Address() string                   { return lr.log.Address.Hex() }
+func (lr *logResolver) Data() string                      { return hexutil.Encode(lr.log.Data) }
+func (lr *logResolver) Removed() bool                     { return lr.log.Removed }
+func (lr *logResolver) Transaction() *transactionResolver { return lr.transaction }
+
+func (lr *logResolver) Topics() []string {
+	topics := []string{}
+	for _, topic := range lr.log.Topics {
+		topics = append(topics, topic.Hex())
+	}
+	return topics
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func longPointer(value *uint64) *Long {
+	if value == nil {
+		return nil
+	}
+	long := Long(*value)
+	return &long
+}
+
+func int32Pointer(value *uint) *int32 {
+	if value == nil {
+		return nil
+	}
+	converted := int32(*value)
+	return &converted
+}
+
+const maxBlockTransactions = 10000
+
+type listArgs struct {
+	Filter *filterInput
+	First  *int32
+	After  *string
+	Chain  *string
+}
+
+type filterInput struct {
+	Status          *string
+	FromBlock       *Long
+	ToBlock         *Long
+	From            *string
+	To              *string
+	ContractAddress *string
+	HasLogs         *bool
+	Order           *string
+}
+
+type chain struct {
+	id      uint64
+	backend rpcservices.ChainBackend
+	client  client
+	rpc     rpcClient
+	storage storage
+}
+
+type resolver struct {
+	storage  storage
+	chains   chainRegistry
+	backends map[uint64]rpcservices.ChainBackend
+}
+
+type connectionResolver struct {
+	transactions []*transactionResolver
+	reply        rpcservices.ListTransactionsReply
+}
+
+type transactionResolver struct {
+	chain *chain
+	tx    model.Transaction
+}
+
+// The hash is the one reported by the node
+type blockResolver struct {
+	chain  *chain
+	header *types.Header
+	hash   common.Hash
+}
+
+type logResolver struct {
+	transaction *transactionResolver
+	log         *types.Log
+}
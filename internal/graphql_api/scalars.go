@@ -0,0 +1,47 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Long is a 64-bit integer, GraphQL Int has 32 bits. It is read from numbers and decimal strings.
+type Long int64
+
+func (Long) ImplementsGraphQLType(name string) bool {
+	return name == "Long"
+}
+
+func (l *Long) UnmarshalGraphQL(input interface{}) error {
+	switch value := input.(type) {
+	case int32:
+		*l = Long(value)
+	case int64:
+		*l = Long(value)
+	case float64:
+		if value != float64(int64(value)) {
+			return fmt.Errorf("invalid Long (%v)", value)
+		}
+		*l = Long(value)
+	case string:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid Long (%s)", value)
+		}
+		*l = Long(parsed)
+	default:
+		return fmt.Errorf("unexpected type %T for Long", input)
+	}
+	return nil
+}
+
+func (l *Long) uint64Pointer() (*uint64, error) {
+	if l == nil {
+		return nil, nil
+	}
+	if *l < 0 {
+		return nil, fmt.Errorf("negative block number (%d)", *l)
+	}
+	value := uint64(*l)
+	return &value, nil
+}
@@ -0,0 +1,83 @@
+package graphqlapi
+
+const schema = `
+scalar Long
+
+schema {
+	query: Query
+}
+
+type Query {
+	# Cached transactions are read from the database, pending and uncached ones are fetched from the node.
+	# With a token the transactions are added to the ones of the user.
+	transaction(hash: String!, chain: String): Transaction
+	transactions(hashes: [String!]!, chain: String): [Transaction!]!
+	allTransactions(filter: TransactionFilter, first: Int, after: String, chain: String): TransactionConnection!
+	# Needs a token.
+	myTransactions(filter: TransactionFilter, first: Int, after: String, chain: String): TransactionConnection!
+	# The head block when neither the number nor the hash is given.
+	block(number: Long, hash: String, chain: String): Block
+}
+
+input TransactionFilter {
+	status: String
+	fromBlock: Long
+	toBlock: Long
+	from: String
+	to: String
+	contractAddress: String
+	hasLogs: Boolean
+	order: String
+}
+
+type TransactionConnection {
+	transactions: [Transaction!]!
+	nextCursor: String
+}
+
+type Transaction {
+	chainId: Long!
+	hash: String!
+	status: String!
+	blockHash: String
+	blockNumber: Long
+	transactionIndex: Int
+	from: String!
+	fromName: String
+	to: String
+	toName: String
+	contractAddress: String
+	contractAddressName: String
+	logsCount: Int
+	input: String!
+	value: String!
+	nonce: Long
+	replacedBy: String
+	firstSeenAt: Long
+	verified: Boolean!
+	receiptVerified: Boolean!
+	finalized: Boolean!
+	block: Block
+	logs: [Log!]!
+}
+
+type Block {
+	chainId: Long!
+	number: Long!
+	hash: String!
+	parentHash: String!
+	timestamp: Long!
+	transactionsCount: Int!
+	# Only the cached transactions of the block.
+	transactions: [Transaction!]!
+}
+
+type Log {
+	index: Int!
+	address: String!
+	topics: [String!]!
+	data: String!
+	removed: Boolean!
+	transaction: Transaction!
+}
+`
@@ -0,0 +1,267 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+
+	"github.com/avalkov/eth-node-interaction/internal/chains"
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func NewImporter(storage storage, chains chainRegistry, confirmations uint64) *importer {
+	return &importer{
+		storage:       storage,
+		chains:        chains,
+		confirmations: confirmations,
+	}
+}
+
+// Import validates the rows one by one and inserts the valid ones in batches. Invalid rows
+// are written to errorLog with their line number and skipped, cached transactions are kept.
+// Mined rows with at least the configured confirmations are stored as finalized.
+func (i *importer) Import(ctx context.Context, r io.Reader, request Request, errorLog io.Writer) (Report, error) {
+	chain, err := i.chains.Get(request.Chain)
+	if err != nil {
+		return Report{}, err
+	}
+
+	var reader rowReader
+	switch request.Format {
+	case CsvFormat:
+		if reader, err = newCsvReader(r); err != nil {
+			return Report{}, err
+		}
+	case NdjsonFormat:
+		reader = &ndjsonReader{reader: bufio.NewReader(r)}
+	default:
+		return Report{}, fmt.Errorf("unknown format (%s)", request.Format)
+	}
+
+	head, err := chain.Client.BlockNumber(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to get head of chain (%d): %s", chain.ID, err)
+	}
+
+	report := Report{}
+	batch := []model.Transaction{}
+
+	flush := func() error {
+		inserted, err := i.storage.StoreImportedTxs(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("failed to store batch ending at line %d: %s", report.Lines, err)
+		}
+		report.Imported += inserted
+		report.Cached += int64(len(batch)) - inserted
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		line, fields, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var invalidRow *rowError
+		if err != nil && !errors.As(err, &invalidRow) {
+			return report, err
+		}
+		report.Lines = line
+
+		if err == nil {
+			var tx model.Transaction
+			if tx, err = parseTx(fields, chain.ID); err == nil && request.SampleRate > 0 && rand.Float64() < request.SampleRate {
+				report.Verified++
+				err = verifyTx(ctx, chain.Client, tx)
+			}
+			if err == nil {
+				if tx.BlockNumber != nil && head >= *tx.BlockNumber && head-*tx.BlockNumber+1 >= i.confirmations {
+					tx.Finalized = true
+				}
+				batch = append(batch, tx)
+			}
+		}
+
+		if err != nil {
+			report.Invalid++
+			fmt.Fprintf(errorLog, "line %d: %s\n", line, err)
+			continue
+		}
+
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+func newCsvReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %s", err)
+	}
+
+	columns := make([]string, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if _, ok := knownFields[name]; !ok {
+			return nil, fmt.Errorf("unknown column (%s)", name)
+		}
+		columns[i] = name
+	}
+
+	return &csvReader{reader: reader, columns: columns}, nil
+}
+
+func (cr *csvReader) next() (int, map[string]string, error) {
+	record, err := cr.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return parseErr.StartLine, nil, &rowError{parseErr.Err}
+		}
+		return 0, nil, err
+	}
+	line, _ := cr.reader.FieldPos(0)
+
+	fields := make(map[string]string)
+	for i, value := range record {
+		if value != "" {
+			fields[cr.columns[i]] = value
+		}
+	}
+
+	return line, fields, nil
+}
+
+// Values are read as text, so NDJSON and CSV rows are validated the same way.
+func (nr *ndjsonReader) next() (int, map[string]string, error) {
+	for {
+		content, err := nr.reader.ReadBytes('\n')
+		if len(content) == 0 && err != nil {
+			return nr.line, nil, err
+		}
+		nr.line++
+
+		content = bytes.TrimSpace(content)
+		if len(content) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+
+		var values map[string]interface{}
+		if err := decoder.Decode(&values); err != nil {
+			return nr.line, nil, &rowError{fmt.Errorf("invalid json: %s", err)}
+		}
+
+		fields := make(map[string]string)
+		for name, value := range values {
+			if _, ok := knownFields[name]; !ok {
+				return nr.line, nil, &rowError{fmt.Errorf("unknown field (%s)", name)}
+			}
+
+			switch v := value.(type) {
+			case nil:
+			case string:
+				fields[name] = v
+			case json.Number:
+				fields[name] = v.String()
+			case bool:
+				fields[name] = fmt.Sprint(v)
+			default:
+				return nr.line, nil, &rowError{fmt.Errorf("field (%s) is not a scalar", name)}
+			}
+		}
+
+		return nr.line, fields, nil
+	}
+}
+
+func (e *rowError) Error() string {
+	return e.err.Error()
+}
+
+type Format string
+
+const (
+	CsvFormat    Format = "csv"
+	NdjsonFormat Format = "ndjson"
+)
+
+// Request selects the format and the chain of the rows. SampleRate is the share of mined
+// transactions compared with the node before they are stored, zero disables it.
+type Request struct {
+	Format     Format
+	Chain      string
+	SampleRate float64
+}
+
+// Report counts the rows by outcome. Lines is the last line read.
+type Report struct {
+	Lines    int
+	Imported int64
+	Cached   int64
+	Invalid  int64
+	Verified int64
+}
+
+const batchSize = 500
+
+type rowReader interface {
+	next() (int, map[string]string, error)
+}
+
+// rowError is a row that cannot be read, reading continues with the next one.
+type rowError struct {
+	err error
+}
+
+type csvReader struct {
+	reader  *csv.Reader
+	columns []string
+}
+
+type ndjsonReader struct {
+	reader *bufio.Reader
+	line   int
+}
+
+type storage interface {
+	StoreImportedTxs(ctx context.Context, transactions []model.Transaction) (int64, error)
+}
+
+type chainRegistry interface {
+	Get(selector string) (*chains.Chain, error)
+}
+
+type client interface {
+	TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+type importer struct {
+	storage       storage
+	chains        chainRegistry
+	confirmations uint64
+}
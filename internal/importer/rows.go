@@ -0,0 +1,205 @@
+package importer
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// parseTx builds the transaction from the fields of model.Transaction. The status is also
+// accepted as "status", the name used by exports. Verification flags are not imported.
+func parseTx(fields map[string]string, chainID uint64) (model.Transaction, error) {
+	tx := model.Transaction{ChainID: chainID}
+
+	if value, ok := fields["chainId"]; ok {
+		rowChainID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return model.Transaction{}, fmt.Errorf("invalid chainId (%s)", value)
+		}
+		if rowChainID != chainID {
+			return model.Transaction{}, fmt.Errorf("chainId %d does not match the imported chain %d", rowChainID, chainID)
+		}
+	}
+
+	hash, err := parseHash(fields, "transactionHash")
+	if err != nil {
+		return model.Transaction{}, err
+	}
+	if hash == nil {
+		return model.Transaction{}, errors.New("missing transactionHash")
+	}
+	tx.TransactionHash = *hash
+
+	status, ok := fields["transactionStatus"]
+	if !ok {
+		status, ok = fields["status"]
+	}
+	if !ok {
+		return model.Transaction{}, errors.New("missing transactionStatus")
+	}
+	if tx.TransactionStatus, err = model.ParseTxStatus(status); err != nil {
+		return model.Transaction{}, err
+	}
+
+	from, err := parseAddress(fields, "from")
+	if err != nil {
+		return model.Transaction{}, err
+	}
+	if from == nil {
+		return model.Transaction{}, errors.New("missing from")
+	}
+	tx.From = *from
+
+	if tx.To, err = parseAddress(fields, "to"); err != nil {
+		return model.Transaction{}, err
+	}
+	if tx.ContractAddress, err = parseAddress(fields, "contractAddress"); err != nil {
+		return model.Transaction{}, err
+	}
+	if tx.BlockHash, err = parseHash(fields, "blockHash"); err != nil {
+		return model.Transaction{}, err
+	}
+	if tx.ReplacedBy, err = parseHash(fields, "replacedBy"); err != nil {
+		return model.Transaction{}, err
+	}
+	if tx.BlockNumber, err = parseUint(fields, "blockNumber"); err != nil {
+		return model.Transaction{}, err
+	}
+	if tx.Nonce, err = parseUint(fields, "nonce"); err != nil {
+		return model.Transaction{}, err
+	}
+
+	if transactionIndex, err := parseUint(fields, "transactionIndex"); err != nil {
+		return model.Transaction{}, err
+	} else if transactionIndex != nil {
+		index := uint(*transactionIndex)
+		tx.TransactionIndex = &index
+	}
+
+	if logsCount, err := parseUint(fields, "logsCount"); err != nil {
+		return model.Transaction{}, err
+	} else if logsCount != nil {
+		count := int(*logsCount)
+		tx.LogsCount = &count
+	}
+
+	if firstSeenAt, err := parseUint(fields, "firstSeenAt"); err != nil {
+		return model.Transaction{}, err
+	} else if firstSeenAt != nil {
+		if *firstSeenAt > math.MaxInt64 {
+			return model.Transaction{}, fmt.Errorf("invalid firstSeenAt (%d)", *firstSeenAt)
+		}
+		seenAt := int64(*firstSeenAt)
+		tx.FirstSeenAt = &seenAt
+	}
+
+	// Inputs are cached as hex without the prefix
+	input := strings.ToLower(strings.TrimPrefix(fields["input"], "0x"))
+	if _, err := hex.DecodeString(input); err != nil {
+		return model.Transaction{}, errors.New("input is not hex")
+	}
+	tx.Input = input
+
+	// The column holds a BIGINT
+	value, ok := new(big.Int).SetString(fields["value"], 10)
+	if !ok || value.Sign() < 0 || !value.IsInt64() {
+		return model.Transaction{}, fmt.Errorf("invalid value (%s)", fields["value"])
+	}
+	tx.Value = value.String()
+
+	if value, ok := fields["finalized"]; ok {
+		if tx.Finalized, err = strconv.ParseBool(value); err != nil {
+			return model.Transaction{}, fmt.Errorf("invalid finalized (%s)", value)
+		}
+	}
+
+	isMined := tx.TransactionStatus == model.Successful || tx.TransactionStatus == model.Failed
+	if isMined && (tx.BlockHash == nil || tx.BlockNumber == nil) {
+		return model.Transaction{}, errors.New("mined transaction without blockHash and blockNumber")
+	}
+	if tx.BlockNumber != nil && *tx.BlockNumber >= model.PendingBlockNumber {
+		return model.Transaction{}, fmt.Errorf("blockNumber (%d) is out of range", *tx.BlockNumber)
+	}
+	if !isMined && (tx.BlockHash != nil || tx.BlockNumber != nil || tx.TransactionIndex != nil || tx.Finalized) {
+		return model.Transaction{}, fmt.Errorf("%s transaction with block data", tx.TransactionStatus)
+	}
+	if tx.ReplacedBy != nil && tx.TransactionStatus != model.Replaced {
+		return model.Transaction{}, fmt.Errorf("%s transaction with replacedBy", tx.TransactionStatus)
+	}
+
+	return tx, nil
+}
+
+func parseHash(fields map[string]string, name string) (*string, error) {
+	value, ok := fields[name]
+	if !ok {
+		return nil, nil
+	}
+
+	if len(value) != 2+2*common.HashLength || !strings.HasPrefix(value, "0x") {
+		return nil, fmt.Errorf("invalid %s (%s)", name, value)
+	}
+	if _, err := hex.DecodeString(value[2:]); err != nil {
+		return nil, fmt.Errorf("invalid %s (%s)", name, value)
+	}
+
+	hash := common.HexToHash(value).Hex()
+	return &hash, nil
+}
+
+func parseAddress(fields map[string]string, name string) (*string, error) {
+	value, ok := fields[name]
+	if !ok {
+		return nil, nil
+	}
+
+	if !common.IsHexAddress(value) {
+		return nil, fmt.Errorf("invalid %s (%s)", name, value)
+	}
+
+	address := common.HexToAddress(value).Hex()
+	return &address, nil
+}
+
+func parseUint(fields map[string]string, name string) (*uint64, error) {
+	value, ok := fields[name]
+	if !ok {
+		return nil, nil
+	}
+
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s (%s)", name, value)
+	}
+
+	return &number, nil
+}
+
+var knownFields = map[string]struct{}{
+	"chainId":           {},
+	"transactionHash":   {},
+	"transactionStatus": {},
+	"status":            {},
+	"blockHash":         {},
+	"blockNumber":       {},
+	"transactionIndex":  {},
+	"from":              {},
+	"to":                {},
+	"contractAddress":   {},
+	"logsCount":         {},
+	"input":             {},
+	"value":             {},
+	"nonce":             {},
+	"replacedBy":        {},
+	"firstSeenAt":       {},
+	"verified":          {},
+	"receiptVerified":   {},
+	"finalized":         {},
+}
@@ -0,0 +1,92 @@
+package importer
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// verifyTx compares a mined row with the transaction and the receipt from the node. Rows that
+// are not mined change over time and are refreshed once stored, so they are not compared.
+func verifyTx(ctx context.Context, client client, tx model.Transaction) error {
+	if tx.BlockHash == nil {
+		return nil
+	}
+
+	hash := common.HexToHash(tx.TransactionHash)
+
+	rawTx, isPending, err := client.TransactionByHash(ctx, hash)
+	if err != nil {
+		return fmt.Errorf("failed to get tx from node: %s", err)
+	}
+	if isPending {
+		return fmt.Errorf("tx is pending on node")
+	}
+
+	receipt, err := client.TransactionReceipt(ctx, hash)
+	if err != nil {
+		return fmt.Errorf("failed to get receipt from node: %s", err)
+	}
+
+	from, err := types.Sender(types.LatestSignerForChainID(rawTx.ChainId()), rawTx)
+	if err != nil {
+		return fmt.Errorf("failed to recover sender: %s", err)
+	}
+
+	compared := []comparedField{
+		{"blockHash", *tx.BlockHash, receipt.BlockHash.Hex()},
+		{"blockNumber", *tx.BlockNumber, receipt.BlockNumber.Uint64()},
+		{"transactionStatus", tx.TransactionStatus, model.TxStatus(receipt.Status)},
+		{"from", tx.From, from.Hex()},
+		{"to", addressValue(tx.To), addressValue(addressPointer(rawTx.To()))},
+		{"input", tx.Input, hex.EncodeToString(rawTx.Data())},
+		{"value", tx.Value, rawTx.Value().String()},
+	}
+
+	if tx.Nonce != nil {
+		compared = append(compared, comparedField{"nonce", *tx.Nonce, rawTx.Nonce()})
+	}
+	if tx.TransactionIndex != nil {
+		compared = append(compared, comparedField{"transactionIndex", *tx.TransactionIndex, receipt.TransactionIndex})
+	}
+	if tx.LogsCount != nil {
+		compared = append(compared, comparedField{"logsCount", *tx.LogsCount, len(receipt.Logs)})
+	}
+	if rawTx.To() == nil {
+		contractAddress := receipt.ContractAddress.Hex()
+		compared = append(compared, comparedField{"contractAddress", addressValue(tx.ContractAddress), contractAddress})
+	}
+
+	for _, field := range compared {
+		if field.row != field.canonical {
+			return fmt.Errorf("%s (%v) does not match the node (%v)", field.name, field.row, field.canonical)
+		}
+	}
+
+	return nil
+}
+
+func addressPointer(address *common.Address) *string {
+	if address == nil {
+		return nil
+	}
+	hex := address.Hex()
+	return &hex
+}
+
+func addressValue(address *string) string {
+	if address == nil {
+		return ""
+	}
+	return *address
+}
+
+type comparedField struct {
+	name      string
+	row       interface{}
+	canonical interface{}
+}
@@ -0,0 +1,141 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// NewGenerator derives JSON Schemas from Go types through their json tags. Named structs are
+// collected as components and referenced, so OpenAPI and OpenRPC documents can share them.
+func NewGenerator() *generator {
+	return &generator{components: make(map[string]Schema)}
+}
+
+// Schema returns the schema of the type of value. Pointers are nullable, fields without
+// omitempty are required.
+func (g *generator) Schema(value interface{}) Schema {
+	return g.schema(reflect.TypeOf(value))
+}
+
+// Components are the schemas of the named structs met so far, by type name.
+func (g *generator) Components() map[string]Schema {
+	return g.components
+}
+
+func (g *generator) schema(t reflect.Type) Schema {
+	if t == nil || t == rawMessageType {
+		return Schema{}
+	}
+
+	if t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) {
+		// Custom encodings of this codebase and go-ethereum are hex or decimal strings
+		return Schema{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return nullable(g.schema(t.Elem()))
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Schema{"type": "string"}
+		}
+		return Schema{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		if _, ok := g.components[t.Name()]; !ok {
+			// Registered before the fields, so recursive types end
+			g.components[t.Name()] = Schema{}
+			g.components[t.Name()] = g.object(t)
+		}
+		return Schema{"$ref": componentsPath + t.Name()}
+	}
+
+	return Schema{}
+}
+
+func (g *generator) object(t reflect.Type) Schema {
+	properties := Schema{}
+	required := []string{}
+	g.addFields(t, properties, &required)
+
+	object := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		object["required"] = required
+	}
+	return object
+}
+
+// Embedded structs without a json name are flattened, as encoding/json does.
+func (g *generator) addFields(t reflect.Type, properties Schema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			g.addFields(field.Type, properties, required)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = g.schema(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func nullable(schema Schema) Schema {
+	switch value := schema["type"].(type) {
+	case string:
+		nullableSchema := Schema{}
+		for key, v := range schema {
+			nullableSchema[key] = v
+		}
+		nullableSchema["type"] = []string{value, "null"}
+		return nullableSchema
+	case nil:
+		if _, ok := schema["$ref"]; ok {
+			return Schema{"oneOf": []Schema{schema, {"type": "null"}}}
+		}
+	}
+	return schema
+}
+
+const componentsPath = "#/components/schemas/"
+
+var (
+	marshalerType  = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+type Schema map[string]interface{}
+
+type generator struct {
+	components map[string]Schema
+}
@@ -0,0 +1,192 @@
+package mempool
+
+import (
+	"container/list"
+	"encoding/hex"
+	"sort"
+	"sync"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func newIndex(chainID uint64, capacity int) *index {
+	return &index{
+		chainID:     chainID,
+		capacity:    capacity,
+		entries:     make(map[common.Hash]*list.Element),
+		order:       list.New(),
+		bySender:    make(map[common.Address]map[common.Hash]struct{}),
+		byRecipient: make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+// add keeps the first time a transaction was seen. The oldest entries are evicted
+// once the capacity is reached.
+func (i *index) add(tx *types.Transaction, from common.Address, seenAt int64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if _, ok := i.entries[tx.Hash()]; ok {
+		return
+	}
+
+	for i.order.Len() >= i.capacity {
+		i.remove(i.order.Front())
+	}
+
+	i.entries[tx.Hash()] = i.order.PushBack(&entry{tx: tx, from: from, firstSeenAt: seenAt})
+
+	addToSet(i.bySender, from, tx.Hash())
+	if tx.To() != nil {
+		addToSet(i.byRecipient, *tx.To(), tx.Hash())
+	}
+}
+
+func (i *index) has(hash common.Hash) bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	_, ok := i.entries[hash]
+	return ok
+}
+
+// Included transactions are kept until evicted, so their first-seen time stays available.
+func (i *index) markIncluded(hashes []common.Hash) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, hash := range hashes {
+		if element, ok := i.entries[hash]; ok {
+			element.Value.(*entry).included = true
+		}
+	}
+}
+
+func (i *index) prune(before int64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for element := i.order.Front(); element != nil && element.Value.(*entry).firstSeenAt < before; element = i.order.Front() {
+		i.remove(element)
+	}
+}
+
+func (i *index) pending(hash common.Hash) (*types.Transaction, int64, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	element, ok := i.entries[hash]
+	if !ok || element.Value.(*entry).included {
+		return nil, 0, false
+	}
+
+	e := element.Value.(*entry)
+	return e.tx, e.firstSeenAt, true
+}
+
+func (i *index) firstSeen(hash common.Hash) *int64 {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	element, ok := i.entries[hash]
+	if !ok {
+		return nil
+	}
+
+	firstSeenAt := element.Value.(*entry).firstSeenAt
+	return &firstSeenAt
+}
+
+func (i *index) pendingByAddress(address common.Address) []model.Transaction {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	hashes := make(map[common.Hash]struct{})
+	for hash := range i.bySender[address] {
+		hashes[hash] = struct{}{}
+	}
+	for hash := range i.byRecipient[address] {
+		hashes[hash] = struct{}{}
+	}
+
+	transactions := []model.Transaction{}
+	for hash := range hashes {
+		e := i.entries[hash].Value.(*entry)
+		if !e.included {
+			transactions = append(transactions, i.toModel(e))
+		}
+	}
+
+	sort.Slice(transactions, func(a, b int) bool {
+		return *transactions[a].FirstSeenAt < *transactions[b].FirstSeenAt
+	})
+
+	return transactions
+}
+
+func (i *index) remove(element *list.Element) {
+	e := i.order.Remove(element).(*entry)
+	hash := e.tx.Hash()
+
+	delete(i.entries, hash)
+	removeFromSet(i.bySender, e.from, hash)
+	if e.tx.To() != nil {
+		removeFromSet(i.byRecipient, *e.tx.To(), hash)
+	}
+}
+
+func (i *index) toModel(e *entry) model.Transaction {
+	nonce := e.tx.Nonce()
+	firstSeenAt := e.firstSeenAt
+
+	tx := model.Transaction{
+		ChainID:           i.chainID,
+		TransactionHash:   e.tx.Hash().Hex(),
+		TransactionStatus: model.Pending,
+		From:              e.from.Hex(),
+		Input:             hex.EncodeToString(e.tx.Data()),
+		Value:             e.tx.Value().String(),
+		Nonce:             &nonce,
+		FirstSeenAt:       &firstSeenAt,
+	}
+
+	if e.tx.To() != nil {
+		to := e.tx.To().Hex()
+		tx.To = &to
+	}
+
+	return tx
+}
+
+func addToSet(sets map[common.Address]map[common.Hash]struct{}, address common.Address, hash common.Hash) {
+	if sets[address] == nil {
+		sets[address] = make(map[common.Hash]struct{})
+	}
+	sets[address][hash] = struct{}{}
+}
+
+func removeFromSet(sets map[common.Address]map[common.Hash]struct{}, address common.Address, hash common.Hash) {
+	delete(sets[address], hash)
+	if len(sets[address]) == 0 {
+		delete(sets, address)
+	}
+}
+
+type entry struct {
+	tx          *types.Transaction
+	from        common.Address
+	firstSeenAt int64
+	included    bool
+}
+
+type index struct {
+	mu          sync.RWMutex
+	chainID     uint64
+	capacity    int
+	entries     map[common.Hash]*list.Element
+	order       *list.List
+	bySender    map[common.Address]map[common.Hash]struct{}
+	byRecipient map[common.Address]map[common.Hash]struct{}
+}
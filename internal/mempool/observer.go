@@ -0,0 +1,188 @@
+package mempool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Subscriptions need a websocket or IPC endpoint of the node.
+func NewObserver(chainID uint64, subscriber subscriber, client client, capacity int, maxAge time.Duration) *observer {
+	return &observer{
+		chainID:    chainID,
+		subscriber: subscriber,
+		client:     client,
+		maxAge:     maxAge,
+		index:      newIndex(chainID, capacity),
+		hashes:     make(chan common.Hash, hashQueueSize),
+	}
+}
+
+// Run follows the pending transactions of the node and resubscribes after failures.
+func (o *observer) Run(ctx context.Context) {
+	for i := 0; i < hashWorkers; i++ {
+		go o.fetchHashes(ctx)
+	}
+
+	for {
+		if err := o.observe(ctx); err != nil {
+			log.Println(fmt.Errorf("mempool observer of chain (%d): %s", o.chainID, err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(resubscribeDelay):
+		}
+	}
+}
+
+func (o *observer) observe(ctx context.Context) error {
+	pending := make(chan json.RawMessage, pendingBufferSize)
+
+	// Nodes that do not support full transactions reject the flag
+	pendingSub, err := o.subscriber.EthSubscribe(ctx, pending, "newPendingTransactions", true)
+	if err != nil {
+		if pendingSub, err = o.subscriber.EthSubscribe(ctx, pending, "newPendingTransactions"); err != nil {
+			return err
+		}
+	}
+	defer pendingSub.Unsubscribe()
+
+	heads := make(chan *types.Header, headBufferSize)
+	headSub, err := o.client.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		return err
+	}
+	defer headSub.Unsubscribe()
+
+	prune := time.NewTicker(pruneInterval)
+	defer prune.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-pendingSub.Err():
+			return err
+		case err := <-headSub.Err():
+			return err
+		case <-prune.C:
+			o.index.prune(time.Now().Add(-o.maxAge).UnixNano())
+		case message := <-pending:
+			o.handlePending(message)
+		case head := <-heads:
+			block, err := o.client.BlockByNumber(ctx, head.Number)
+			if err != nil {
+				log.Println(fmt.Errorf("mempool observer failed to get block (%d): %s", head.Number, err))
+				continue
+			}
+
+			hashes := []common.Hash{}
+			for _, tx := range block.Transactions() {
+				hashes = append(hashes, tx.Hash())
+			}
+			o.index.markIncluded(hashes)
+		}
+	}
+}
+
+// Notifications carry either the full transaction or only its hash.
+func (o *observer) handlePending(message json.RawMessage) {
+	var hash common.Hash
+	if err := json.Unmarshal(message, &hash); err == nil {
+		if o.index.has(hash) {
+			return
+		}
+
+		select {
+		case o.hashes <- hash:
+		default:
+			// The node is faster than the lookups, the transaction is skipped
+		}
+		return
+	}
+
+	tx := new(types.Transaction)
+	if err := json.Unmarshal(message, tx); err != nil {
+		log.Println(fmt.Errorf("mempool observer of chain (%d) got invalid transaction: %s", o.chainID, err))
+		return
+	}
+
+	o.add(tx)
+}
+
+func (o *observer) fetchHashes(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case hash := <-o.hashes:
+			tx, isPending, err := o.client.TransactionByHash(ctx, hash)
+			if err != nil || !isPending {
+				continue
+			}
+			o.add(tx)
+		}
+	}
+}
+
+func (o *observer) add(tx *types.Transaction) {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return
+	}
+	o.index.add(tx, from, time.Now().UnixNano())
+}
+
+// Pending returns the transaction when it is known to be pending, with the time it was first seen.
+func (o *observer) Pending(hash common.Hash) (*types.Transaction, int64, bool) {
+	return o.index.pending(hash)
+}
+
+// FirstSeen returns nil for transactions that were not seen in the mempool or were already evicted.
+func (o *observer) FirstSeen(hash common.Hash) *int64 {
+	return o.index.firstSeen(hash)
+}
+
+// PendingByAddress returns the pending transactions sent by or to the address, oldest first.
+func (o *observer) PendingByAddress(address common.Address) []model.Transaction {
+	return o.index.pendingByAddress(address)
+}
+
+const (
+	hashWorkers       = 8
+	hashQueueSize     = 4096
+	pendingBufferSize = 4096
+	headBufferSize    = 16
+	resubscribeDelay  = 10 * time.Second
+	pruneInterval     = time.Minute
+)
+
+type subscriber interface {
+	EthSubscribe(ctx context.Context, channel interface{}, args ...interface{}) (*rpc.ClientSubscription, error)
+}
+
+type client interface {
+	TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error)
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+}
+
+type observer struct {
+	chainID    uint64
+	subscriber subscriber
+	client     client
+	maxAge     time.Duration
+	index      *index
+	hashes     chan common.Hash
+}
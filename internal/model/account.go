@@ -0,0 +1,10 @@
+package model
+
+type AccountState struct {
+	ChainID     uint64 `json:"chainId" db:"chain_id"`
+	Address     string `json:"address" db:"address"`
+	BlockNumber uint64 `json:"blockNumber" db:"block_number"`
+	Balance     string `json:"balance" db:"balance"`
+	Nonce       uint64 `json:"nonce" db:"nonce"`
+	CodeHash    string `json:"codeHash" db:"code_hash"`
+}
@@ -0,0 +1,10 @@
+package model
+
+type Block struct {
+	ChainID           uint64 `json:"chainId"`
+	Number            uint64 `json:"number"`
+	Hash              string `json:"hash"`
+	ParentHash        string `json:"parentHash"`
+	Timestamp         uint64 `json:"timestamp"`
+	TransactionsCount int    `json:"transactionsCount"`
+}
@@ -0,0 +1,28 @@
+package model
+
+type ProxyType string
+
+const (
+	NoProxy             ProxyType = ""
+	Eip1967Proxy        ProxyType = "eip1967"
+	Eip1967BeaconProxy  ProxyType = "eip1967-beacon"
+	Eip1167MinimalProxy ProxyType = "eip1167"
+)
+
+// ContractInfo describes the code at an address. AbiAddress is the address whose ABI
+// applies to calls of the contract, the implementation for proxies.
+type ContractInfo struct {
+	ChainID                 uint64    `json:"chainId" db:"chain_id"`
+	Address                 string    `json:"address" db:"address"`
+	CodeHash                string    `json:"codeHash" db:"code_hash"`
+	CodeSize                int       `json:"codeSize" db:"code_size"`
+	ProxyType               ProxyType `json:"proxyType" db:"proxy_type"`
+	Implementation          *string   `json:"implementation" db:"implementation"`
+	Beacon                  *string   `json:"beacon" db:"beacon"`
+	Admin                   *string   `json:"admin" db:"admin"`
+	CreationTransactionHash *string   `json:"creationTransactionHash" db:"creation_transaction_hash"`
+	BlockNumber             uint64    `json:"blockNumber" db:"block_number"`
+	UpdatedAt               int64     `json:"updatedAt" db:"updated_at"`
+
+	AbiAddress string `json:"abiAddress" db:"-"`
+}
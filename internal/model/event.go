@@ -0,0 +1,45 @@
+package model
+
+type EventType string
+
+const (
+	TxConfirmedEvent EventType = "transaction.confirmed"
+	TxFailedEvent    EventType = "transaction.failed"
+	TxDroppedEvent   EventType = "transaction.dropped"
+	TxFinalizedEvent EventType = "transaction.finalized"
+	TxReorgedEvent   EventType = "transaction.reorged"
+	TxReplacedEvent  EventType = "transaction.replaced"
+	WatchMatchEvent  EventType = "watch.match"
+	NewBlockEvent    EventType = "block.new"
+)
+
+// Event is addressed to a single user, block events to nobody in particular.
+// Only the field matching the type is set.
+type Event struct {
+	Type          EventType     `json:"type"`
+	Username      string        `json:"-"`
+	ChainID       uint64        `json:"chainId"`
+	CreatedAt     int64         `json:"createdAt"`
+	Transaction   *Transaction  `json:"transaction,omitempty"`
+	Confirmations *uint64       `json:"confirmations,omitempty"`
+	Notification  *Notification `json:"notification,omitempty"`
+	Block         *Block        `json:"block,omitempty"`
+}
+
+// TxOwner identifies who asked for a transaction. The token keeps the existing
+// per-token listings working, the username routes events to the user.
+type TxOwner struct {
+	Token    string
+	Username string
+}
+
+// StoredEvent is a transaction event kept in the journal so streams can resume.
+type StoredEvent struct {
+	ID              int64     `json:"id" db:"id"`
+	Username        string    `json:"-" db:"username"`
+	ChainID         uint64    `json:"chainId" db:"chain_id"`
+	TransactionHash string    `json:"transactionHash" db:"transaction_hash"`
+	EventType       EventType `json:"eventType" db:"event_type"`
+	Payload         string    `json:"payload" db:"payload"`
+	CreatedAt       int64     `json:"createdAt" db:"created_at"`
+}
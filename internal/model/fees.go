@@ -0,0 +1,31 @@
+package model
+
+type FeeSuggestions struct {
+	BlockNumber  uint64         `json:"blockNumber"`
+	NextBaseFee  string         `json:"nextBaseFee"`
+	BaseFeeMaxIn []BaseFeeBound `json:"baseFeeMaxIn"`
+	PriorityFees PriorityFees   `json:"priorityFees"`
+	MaxFees      PriorityFees   `json:"maxFees"`
+	TxComparison *FeeComparison `json:"txComparison,omitempty"`
+}
+
+// BaseFeeBound is the highest base fee possible after the given number of full blocks.
+type BaseFeeBound struct {
+	Blocks  uint64 `json:"blocks"`
+	BaseFee string `json:"baseFee"`
+}
+
+type PriorityFees struct {
+	Slow     string `json:"slow"`
+	Standard string `json:"standard"`
+	Fast     string `json:"fast"`
+}
+
+type FeeComparison struct {
+	TransactionHash string `json:"transactionHash"`
+	MaxFee          string `json:"maxFee"`
+	MaxPriorityFee  string `json:"maxPriorityFee"`
+	EffectiveTip    string `json:"effectiveTip"`
+	// One of "underpriced", "slow", "standard", "fast" or "mined"
+	Tier string `json:"tier"`
+}
@@ -0,0 +1,108 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TxFilter narrows down cached transactions. Nil fields do not filter. Address matches
+// the sender, the recipient or the created contract.
+type TxFilter struct {
+	ChainID         uint64
+	FromBlock       *uint64
+	ToBlock         *uint64
+	Address         *string
+	From            *string
+	To              *string
+	ContractAddress *string
+	Status          *TxStatus
+	HasLogs         *bool
+}
+
+// TxQuery selects a page of cached transactions ordered by block number and index, pending
+// ones last. Token limits it to the transactions requested with the token.
+type TxQuery struct {
+	Filter     TxFilter
+	Token      *string
+	After      *TxPosition
+	Descending bool
+	Limit      int
+}
+
+// TxPosition is the sort key of a transaction. Pending transactions take the largest block
+// number and the index -1.
+type TxPosition struct {
+	BlockNumber int64  `json:"b"`
+	Index       int64  `json:"i"`
+	Hash        string `json:"h"`
+}
+
+func PositionOf(tx Transaction) TxPosition {
+	position := TxPosition{BlockNumber: PendingBlockNumber, Index: -1, Hash: tx.TransactionHash}
+	if tx.BlockNumber != nil {
+		position.BlockNumber = int64(*tx.BlockNumber)
+	}
+	if tx.TransactionIndex != nil {
+		position.Index = int64(*tx.TransactionIndex)
+	}
+	return position
+}
+
+// Cursor is opaque to clients.
+func (p TxPosition) Cursor() string {
+	encoded, _ := json.Marshal(p)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func ParseTxCursor(cursor string) (TxPosition, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return TxPosition{}, fmt.Errorf("invalid cursor (%s)", cursor)
+	}
+
+	var position TxPosition
+	if err := json.Unmarshal(decoded, &position); err != nil || position.Hash == "" {
+		return TxPosition{}, fmt.Errorf("invalid cursor (%s)", cursor)
+	}
+
+	return position, nil
+}
+
+// The block_number column is an INT.
+const PendingBlockNumber = 2147483647
+
+// ParseTxStatus accepts the status name or its number.
+func ParseTxStatus(value string) (TxStatus, error) {
+	if number, err := strconv.Atoi(value); err == nil {
+		if number < int(Failed) || number > int(Replaced) {
+			return 0, fmt.Errorf("invalid status (%s)", value)
+		}
+		return TxStatus(number), nil
+	}
+
+	for status, name := range txStatusNames {
+		if strings.EqualFold(value, name) {
+			return status, nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid status (%s)", value)
+}
+
+func (s TxStatus) String() string {
+	if name, ok := txStatusNames[s]; ok {
+		return name
+	}
+	return strconv.Itoa(int(s))
+}
+
+var txStatusNames = map[TxStatus]string{
+	Failed:     "failed",
+	Successful: "successful",
+	Pending:    "pending",
+	Dropped:    "dropped",
+	Replaced:   "replaced",
+}
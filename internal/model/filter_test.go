@@ -0,0 +1,58 @@
+package model
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestTxCursorRoundTrip(t *testing.T) {
+	blockNumber, index := uint64(17_000_000), uint(42)
+
+	tests := []struct {
+		name string
+		tx   Transaction
+		want TxPosition
+	}{
+		{"mined", Transaction{TransactionHash: "0xaa", BlockNumber: &blockNumber, TransactionIndex: &index}, TxPosition{17_000_000, 42, "0xaa"}},
+		{"mined without index", Transaction{TransactionHash: "0xbb", BlockNumber: &blockNumber}, TxPosition{17_000_000, -1, "0xbb"}},
+		{"pending", Transaction{TransactionHash: "0xcc"}, TxPosition{PendingBlockNumber, -1, "0xcc"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			position := PositionOf(test.tx)
+			if position != test.want {
+				t.Fatalf("position = %+v, want %+v", position, test.want)
+			}
+
+			parsed, err := ParseTxCursor(position.Cursor())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed != position {
+				t.Fatalf("cursor decoded to %+v, want %+v", parsed, position)
+			}
+		})
+	}
+}
+
+func TestParseTxCursorRejectsInvalidCursors(t *testing.T) {
+	encode := func(value string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+
+	cursors := []string{
+		"",
+		"not base64!",
+		encode("not json"),
+		encode(`{"b":1,"i":2}`),
+		encode(`{"b":"1","i":2,"h":"0xaa"}`),
+		encode(`[1,2,"0xaa"]`),
+	}
+
+	for _, cursor := range cursors {
+		if position, err := ParseTxCursor(cursor); err == nil {
+			t.Errorf("ParseTxCursor(%q) = %+v", cursor, position)
+		}
+	}
+}
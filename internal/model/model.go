@@ -6,17 +6,31 @@ const (
 	Failed TxStatus = iota
 	Successful
 	Pending
+	Dropped
+	Replaced
 )
 
 type Transaction struct {
+	ChainID           uint64   `json:"chainId" db:"chain_id"`
 	TransactionHash   string   `json:"transactionHash" db:"transaction_hash"`
 	TransactionStatus TxStatus `json:"transactionStatus" db:"transaction_status"`
 	BlockHash         *string  `json:"blockHash" db:"block_hash"`
 	BlockNumber       *uint64  `json:"blockNumber" db:"block_number"`
+	TransactionIndex  *uint    `json:"transactionIndex" db:"transaction_index"`
 	From              string   `json:"from" db:"from_address"`
 	To                *string  `json:"to" db:"to_address"`
 	ContractAddress   *string  `json:"contractAddress" db:"contract_address"`
 	LogsCount         *int     `json:"logsCount" db:"logs_count"`
 	Input             string   `json:"input" db:"input"`
 	Value             string   `json:"value" db:"value"`
+	Nonce             *uint64  `json:"nonce" db:"nonce"`
+	ReplacedBy        *string  `json:"replacedBy" db:"replaced_by"`
+	FirstSeenAt       *int64   `json:"firstSeenAt" db:"first_seen_at"`
+	Verified          bool     `json:"verified" db:"verified"`
+	ReceiptVerified   bool     `json:"receiptVerified" db:"receipt_verified"`
+	Finalized         bool     `json:"finalized" db:"finalized"`
+
+	FromName            *string `json:"fromName,omitempty" db:"-"`
+	ToName              *string `json:"toName,omitempty" db:"-"`
+	ContractAddressName *string `json:"contractAddressName,omitempty" db:"-"`
 }
@@ -0,0 +1,19 @@
+package model
+
+type NonceStatus int
+
+const (
+	NonceReserved NonceStatus = iota
+	NonceSent
+	NonceReleased
+)
+
+type NonceReservation struct {
+	ChainID         uint64      `json:"chainId" db:"chain_id"`
+	Address         string      `json:"address" db:"address"`
+	Nonce           uint64      `json:"nonce" db:"nonce"`
+	Status          NonceStatus `json:"status" db:"status"`
+	TransactionHash *string     `json:"transactionHash" db:"transaction_hash"`
+	ReservedBy      *string     `json:"-" db:"reserved_by"`
+	UpdatedAt       int64       `json:"updatedAt" db:"updated_at"`
+}
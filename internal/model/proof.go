@@ -0,0 +1,22 @@
+package model
+
+type ProvenAccount struct {
+	ChainID      uint64       `json:"chainId"`
+	Address      string       `json:"address"`
+	BlockNumber  uint64       `json:"blockNumber"`
+	BlockHash    string       `json:"blockHash"`
+	StateRoot    string       `json:"stateRoot"`
+	Exists       bool         `json:"exists"`
+	Balance      string       `json:"balance"`
+	Nonce        uint64       `json:"nonce"`
+	CodeHash     string       `json:"codeHash"`
+	StorageRoot  string       `json:"storageRoot"`
+	AccountProof []string     `json:"accountProof"`
+	Storage      []ProvenSlot `json:"storage"`
+}
+
+type ProvenSlot struct {
+	Key   string   `json:"key"`
+	Value string   `json:"value"`
+	Proof []string `json:"proof"`
+}
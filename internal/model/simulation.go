@@ -0,0 +1,21 @@
+package model
+
+import "encoding/json"
+
+type CallRequest struct {
+	From  string  `json:"from"`
+	To    *string `json:"to"`
+	Data  string  `json:"data"`
+	Value string  `json:"value"`
+	Block string  `json:"block"`
+	// Passed to eth_call as is, e.g. {"0x...": {"balance": "0x...", "stateDiff": {...}}}
+	StateOverrides json.RawMessage `json:"stateOverrides,omitempty"`
+}
+
+type Simulation struct {
+	Success      bool    `json:"success"`
+	ReturnData   string  `json:"returnData"`
+	GasEstimate  *uint64 `json:"gasEstimate"`
+	RevertReason *string `json:"revertReason"`
+	Error        *string `json:"error"`
+}
@@ -0,0 +1,29 @@
+package model
+
+type WatchedAddress struct {
+	ID        int64  `json:"id" db:"id"`
+	Username  string `json:"username" db:"username"`
+	ChainID   uint64 `json:"chainId" db:"chain_id"`
+	Address   string `json:"address" db:"address"`
+	CreatedAt int64  `json:"createdAt" db:"created_at"`
+}
+
+type NotificationKind string
+
+const (
+	TransactionNotification NotificationKind = "transaction"
+	LogNotification         NotificationKind = "log"
+)
+
+type Notification struct {
+	ID              int64            `json:"id" db:"id"`
+	Username        string           `json:"username" db:"username"`
+	ChainID         uint64           `json:"chainId" db:"chain_id"`
+	Address         string           `json:"address" db:"address"`
+	Kind            NotificationKind `json:"kind" db:"kind"`
+	TransactionHash string           `json:"transactionHash" db:"transaction_hash"`
+	BlockNumber     uint64           `json:"blockNumber" db:"block_number"`
+	LogIndex        int              `json:"logIndex" db:"log_index"`
+	CreatedAt       int64            `json:"createdAt" db:"created_at"`
+	Acknowledged    bool             `json:"acknowledged" db:"acknowledged"`
+}
@@ -0,0 +1,43 @@
+package model
+
+type WebhookSubscription struct {
+	ID         int64  `json:"id" db:"id"`
+	Username   string `json:"-" db:"username"`
+	Url        string `json:"url" db:"url"`
+	Secret     string `json:"-" db:"secret"`
+	EventTypes string `json:"eventTypes" db:"event_types"`
+	CreatedAt  int64  `json:"createdAt" db:"created_at"`
+}
+
+type DeliveryStatus int
+
+const (
+	DeliveryPending DeliveryStatus = iota
+	DeliveryDelivered
+	DeliveryFailed
+)
+
+type WebhookDelivery struct {
+	ID             int64          `json:"id" db:"id"`
+	SubscriptionID int64          `json:"subscriptionId" db:"subscription_id"`
+	EventType      EventType      `json:"eventType" db:"event_type"`
+	Payload        string         `json:"payload" db:"payload"`
+	Status         DeliveryStatus `json:"status" db:"status"`
+	Attempts       int            `json:"attempts" db:"attempts"`
+	NextAttemptAt  int64          `json:"nextAttemptAt" db:"next_attempt_at"`
+	LastError      *string        `json:"lastError" db:"last_error"`
+	CreatedAt      int64          `json:"createdAt" db:"created_at"`
+	DeliveredAt    *int64         `json:"deliveredAt" db:"delivered_at"`
+
+	Log []WebhookDeliveryAttempt `json:"log,omitempty" db:"-"`
+}
+
+type WebhookDeliveryAttempt struct {
+	ID         int64   `json:"-" db:"id"`
+	DeliveryID int64   `json:"-" db:"delivery_id"`
+	Attempt    int     `json:"attempt" db:"attempt"`
+	StatusCode *int    `json:"statusCode" db:"status_code"`
+	Error      *string `json:"error" db:"error"`
+	DurationMs int64   `json:"durationMs" db:"duration_ms"`
+	CreatedAt  int64   `json:"createdAt" db:"created_at"`
+}
@@ -0,0 +1,208 @@
+package noncemanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Only the addresses are managed, the service does not hold their keys. Only the users may reserve
+// and release their nonces.
+func NewNonceManager(chainID uint64, storage storage, client client, addresses []common.Address, users []string) *nonceManager {
+	managed := make(map[common.Address]struct{})
+	for _, address := range addresses {
+		managed[address] = struct{}{}
+	}
+
+	allowed := make(map[string]struct{})
+	for _, user := range users {
+		allowed[user] = struct{}{}
+	}
+
+	return &nonceManager{
+		chainID:   chainID,
+		storage:   storage,
+		client:    client,
+		addresses: addresses,
+		managed:   managed,
+		allowed:   allowed,
+	}
+}
+
+func (nm *nonceManager) Manages(address common.Address) bool {
+	_, ok := nm.managed[address]
+	return ok
+}
+
+func (nm *nonceManager) Allows(username string) bool {
+	_, ok := nm.allowed[username]
+	return ok
+}
+
+func (nm *nonceManager) Reserve(ctx context.Context, address common.Address, username string) (uint64, error) {
+	if !nm.Manages(address) {
+		return 0, fmt.Errorf("address (%s) is not managed", address.Hex())
+	}
+
+	pendingNonce, err := nm.client.PendingNonceAt(ctx, address)
+	if err != nil {
+		return 0, err
+	}
+	return nm.storage.ReserveNonce(ctx, nm.chainID, address.Hex(), username, pendingNonce)
+}
+
+func (nm *nonceManager) Release(ctx context.Context, address common.Address, username string, nonce uint64) (bool, error) {
+	return nm.storage.ReleaseNonce(ctx, nm.chainID, address.Hex(), username, nonce)
+}
+
+func (nm *nonceManager) MarkSent(ctx context.Context, address common.Address, nonce uint64, txHash common.Hash) error {
+	return nm.storage.MarkNonceSent(ctx, nm.chainID, address.Hex(), nonce, txHash.Hex())
+}
+
+// SendSigned submits a transaction signed with a nonce chosen by the sender, usually one the user
+// reserved before. It fails when another transaction was sent or is being sent with the nonce, or
+// when another user reserved it.
+func (nm *nonceManager) SendSigned(ctx context.Context, from common.Address, username string, tx *types.Transaction) error {
+	pendingNonce, err := nm.client.PendingNonceAt(ctx, from)
+	if err != nil {
+		return err
+	}
+
+	staleBefore := time.Now().Add(-reservationTimeout).UnixNano()
+	if err := nm.storage.ClaimNonce(ctx, nm.chainID, from.Hex(), username, tx.Nonce(), pendingNonce, tx.Hash().Hex(), staleBefore); err != nil {
+		return err
+	}
+
+	return nm.submit(ctx, from, username, tx)
+}
+
+// submit releases the nonce only when the node rejects the transaction. After a timeout or a
+// transport error the node may have accepted it, so the nonce stays reserved until it is mined
+// or reported as a gap.
+func (nm *nonceManager) submit(ctx context.Context, from common.Address, username string, tx *types.Transaction) error {
+	if err := nm.client.SendTransaction(ctx, tx); err != nil && !isKnown(err) {
+		if isRejected(err) {
+			nm.release(from, username, tx.Nonce())
+		}
+		return err
+	}
+
+	if err := nm.MarkSent(ctx, from, tx.Nonce(), tx.Hash()); err != nil {
+		log.Println(fmt.Errorf("failed to mark nonce %d of (%s) as sent: %s", tx.Nonce(), from.Hex(), err))
+	}
+
+	return nil
+}
+
+func (nm *nonceManager) release(from common.Address, username string, nonce uint64) {
+	if _, err := nm.Release(context.Background(), from, username, nonce); err != nil {
+		log.Println(fmt.Errorf("failed to release nonce %d of (%s): %s", nonce, from.Hex(), err))
+	}
+}
+
+// Gaps reconciles the reservations with the node and returns the nonces that block
+// later transactions from being mined and need a filler transaction. A nonce is a gap
+// when the node does not know a transaction for it and no send is in flight.
+func (nm *nonceManager) Gaps(ctx context.Context, address common.Address) ([]uint64, error) {
+	minedNonce, err := nm.client.NonceAt(ctx, address, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	pendingNonce, err := nm.client.PendingNonceAt(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	nextNonce, reservations, err := nm.storage.ReconcileNonces(ctx, nm.chainID, address.Hex(), minedNonce, pendingNonce)
+	if err != nil {
+		return nil, err
+	}
+
+	inFlight := make(map[uint64]struct{})
+	for _, reservation := range reservations {
+		if reservation.Status == model.NonceReserved && time.Since(time.Unix(0, reservation.UpdatedAt)) < reservationTimeout {
+			inFlight[reservation.Nonce] = struct{}{}
+		}
+	}
+
+	gaps := []uint64{}
+	for nonce := pendingNonce; nonce < nextNonce; nonce++ {
+		if _, ok := inFlight[nonce]; !ok {
+			gaps = append(gaps, nonce)
+		}
+	}
+
+	return gaps, nil
+}
+
+// WatchGaps reconciles the managed addresses with the node. Gaps are filled by sending a
+// transaction with the nonce through SendSigned, usually a zero value transfer to the sender.
+func (nm *nonceManager) WatchGaps(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, address := range nm.addresses {
+				gaps, err := nm.Gaps(ctx, address)
+				if err != nil {
+					log.Println(fmt.Errorf("failed to reconcile nonces of (%s) on chain (%d): %s", address.Hex(), nm.chainID, err))
+					continue
+				}
+				if len(gaps) > 0 {
+					log.Printf("nonce gaps of (%s) on chain (%d) wait for filler transactions: %v", address.Hex(), nm.chainID, gaps)
+				}
+			}
+		}
+	}
+}
+
+// The node answers rejections with JSON-RPC errors, anything else may have reached it.
+func isRejected(err error) bool {
+	var rpcErr rpc.Error
+	return errors.As(err, &rpcErr) && !strings.Contains(err.Error(), "nonce too low")
+}
+
+// isKnown reports rejections of a transaction the node already has.
+func isKnown(err error) bool {
+	return strings.Contains(err.Error(), "already known")
+}
+
+// Reservations older than this are considered abandoned by a crashed sender.
+const reservationTimeout = 5 * time.Minute
+
+type storage interface {
+	ReserveNonce(ctx context.Context, chainID uint64, address, username string, pendingNonce uint64) (uint64, error)
+	ReleaseNonce(ctx context.Context, chainID uint64, address, username string, nonce uint64) (bool, error)
+	MarkNonceSent(ctx context.Context, chainID uint64, address string, nonce uint64, txHash string) error
+	ClaimNonce(ctx context.Context, chainID uint64, address, username string, nonce, pendingNonce uint64, txHash string, staleBefore int64) error
+	ReconcileNonces(ctx context.Context, chainID uint64, address string, minedNonce, pendingNonce uint64) (uint64, []model.NonceReservation, error)
+}
+
+type client interface {
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+}
+
+type nonceManager struct {
+	chainID   uint64
+	storage   storage
+	client    client
+	addresses []common.Address
+	managed   map[common.Address]struct{}
+	allowed   map[string]struct{}
+}
@@ -0,0 +1,236 @@
+package proofs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/avalkov/eth-node-interaction/internal/chains"
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+func NewProver(chainID uint64, headers headerClient, proofs proofClient) *prover {
+	return &prover{
+		chainID: chainID,
+		headers: headers,
+		proofs:  proofs,
+	}
+}
+
+// ProveAccount fetches eth_getProof for the account and verifies the account proof
+// against the block's stateRoot and every storage proof against the account's storageRoot.
+func (p *prover) ProveAccount(ctx context.Context, address common.Address, slots []string, block string) (model.ProvenAccount, error) {
+	blockNumber, err := blockNumberOf(block)
+	if err != nil {
+		return model.ProvenAccount{}, err
+	}
+
+	header, blockHash, err := p.header(ctx, blockNumber)
+	if err != nil {
+		return model.ProvenAccount{}, fmt.Errorf("failed to get header of block (%s): %s", block, err)
+	}
+
+	result, err := p.proofs.GetProof(ctx, address, slots, header.Number)
+	if err != nil {
+		return model.ProvenAccount{}, fmt.Errorf("eth_getProof failed: %s", err)
+	}
+
+	if result.Address != address {
+		return model.ProvenAccount{}, fmt.Errorf("proof is for (%s) instead of (%s)", result.Address.Hex(), address.Hex())
+	}
+
+	exists, err := verifyAccount(header.Root, address, result)
+	if err != nil {
+		return model.ProvenAccount{}, err
+	}
+
+	provenAccount := model.ProvenAccount{
+		ChainID:      p.chainID,
+		Address:      address.Hex(),
+		BlockNumber:  header.Number.Uint64(),
+		BlockHash:    blockHash.Hex(),
+		StateRoot:    header.Root.Hex(),
+		Exists:       exists,
+		Balance:      result.Balance.String(),
+		Nonce:        result.Nonce,
+		CodeHash:     result.CodeHash.Hex(),
+		StorageRoot:  result.StorageHash.Hex(),
+		AccountProof: result.AccountProof,
+		Storage:      []model.ProvenSlot{},
+	}
+
+	if len(result.StorageProof) != len(slots) {
+		return model.ProvenAccount{}, fmt.Errorf("node returned %d storage proofs for %d slots", len(result.StorageProof), len(slots))
+	}
+
+	for i, slot := range slots {
+		storageProof := result.StorageProof[i]
+
+		value, err := verifySlot(result.StorageHash, common.HexToHash(slot), storageProof.Proof)
+		if err != nil {
+			return model.ProvenAccount{}, fmt.Errorf("storage proof of slot (%s): %s", slot, err)
+		}
+
+		if storageProof.Value == nil || value.Cmp(storageProof.Value) != 0 {
+			return model.ProvenAccount{}, fmt.Errorf("slot (%s) value %v does not match the proven value %s", slot, storageProof.Value, value)
+		}
+
+		provenAccount.Storage = append(provenAccount.Storage, model.ProvenSlot{
+			Key:   common.HexToHash(slot).Hex(),
+			Value: common.BigToHash(value).Hex(),
+			Proof: storageProof.Proof,
+		})
+	}
+
+	return provenAccount, nil
+}
+
+// header returns the header with the hash reported by the node. The header is hashed locally
+// as well, so the proven stateRoot belongs to the returned block hash.
+func (p *prover) header(ctx context.Context, number *big.Int) (*types.Header, common.Hash, error) {
+	blockParam := "latest"
+	if number != nil {
+		blockParam = hexutil.EncodeBig(number)
+	}
+
+	var raw json.RawMessage
+	if err := p.headers.CallContext(ctx, &raw, "eth_getBlockByNumber", blockParam, false); err != nil {
+		return nil, common.Hash{}, err
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, common.Hash{}, ethereum.NotFound
+	}
+
+	var header types.Header
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return nil, common.Hash{}, err
+	}
+
+	var block struct {
+		Hash common.Hash `json:"hash"`
+	}
+	if err := json.Unmarshal(raw, &block); err != nil {
+		return nil, common.Hash{}, err
+	}
+
+	if header.Hash() != block.Hash {
+		return nil, common.Hash{}, fmt.Errorf("header of block (%s) hashes to (%s)", block.Hash.Hex(), header.Hash().Hex())
+	}
+
+	return &header, block.Hash, nil
+}
+
+func verifyAccount(stateRoot common.Hash, address common.Address, result *gethclient.AccountResult) (bool, error) {
+	value, err := verifyProof(stateRoot, address.Bytes(), result.AccountProof)
+	if err != nil {
+		return false, fmt.Errorf("invalid account proof: %s", err)
+	}
+
+	// A valid proof of absence means the account does not exist and all its fields are empty
+	if value == nil {
+		if result.Nonce != 0 || (result.Balance != nil && result.Balance.Sign() != 0) {
+			return false, errors.New("proof of absence for an account reported with nonce or balance")
+		}
+		return false, nil
+	}
+
+	var account types.StateAccount
+	if err := rlp.DecodeBytes(value, &account); err != nil {
+		return false, fmt.Errorf("failed to decode proven account: %s", err)
+	}
+
+	switch {
+	case account.Nonce != result.Nonce:
+		return false, fmt.Errorf("nonce %d does not match the proven nonce %d", result.Nonce, account.Nonce)
+	case result.Balance == nil || account.Balance.ToBig().Cmp(result.Balance) != 0:
+		return false, fmt.Errorf("balance %v does not match the proven balance %s", result.Balance, account.Balance)
+	case account.Root != result.StorageHash:
+		return false, fmt.Errorf("storage root %s does not match the proven root %s", result.StorageHash.Hex(), account.Root.Hex())
+	case !bytes.Equal(account.CodeHash, result.CodeHash.Bytes()):
+		return false, fmt.Errorf("code hash %s does not match the proven code hash %s", result.CodeHash.Hex(), hexutil.Encode(account.CodeHash))
+	}
+
+	return true, nil
+}
+
+func verifySlot(storageRoot common.Hash, slot common.Hash, proof []string) (*big.Int, error) {
+	value, err := verifyProof(storageRoot, slot.Bytes(), proof)
+	if err != nil {
+		return nil, err
+	}
+
+	if value == nil {
+		return big.NewInt(0), nil
+	}
+
+	var content []byte
+	if err := rlp.DecodeBytes(value, &content); err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(content), nil
+}
+
+// Both tries are keyed by the keccak hash of the key and their nodes are referenced by
+// the keccak hash of their RLP encoding.
+func verifyProof(root common.Hash, key []byte, proof []string) ([]byte, error) {
+	proofDb := memorydb.New()
+	for _, encodedNode := range proof {
+		node, err := hexutil.Decode(encodedNode)
+		if err != nil {
+			return nil, err
+		}
+		if err := proofDb.Put(crypto.Keccak256(node), node); err != nil {
+			return nil, err
+		}
+	}
+
+	return trie.VerifyProof(root, crypto.Keccak256(key), proofDb)
+}
+
+func blockNumberOf(block string) (*big.Int, error) {
+	blockParam, err := chains.BlockParam(block)
+	if err != nil {
+		return nil, err
+	}
+
+	switch blockParam {
+	case "latest":
+		return nil, nil
+	case "earliest":
+		return big.NewInt(0), nil
+	}
+
+	number, err := hexutil.DecodeUint64(blockParam)
+	if err != nil {
+		return nil, fmt.Errorf("block (%s) is not supported for proofs", block)
+	}
+
+	return new(big.Int).SetUint64(number), nil
+}
+
+type headerClient interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+type proofClient interface {
+	GetProof(ctx context.Context, account common.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error)
+}
+
+type prover struct {
+	chainID uint64
+	headers headerClient
+	proofs  proofClient
+}
@@ -0,0 +1,255 @@
+package proofs
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/holiman/uint256"
+)
+
+func TestProveAccount(t *testing.T) {
+	state := newTestState(t)
+
+	tests := []struct {
+		name    string
+		tamper  func(result *gethclient.AccountResult)
+		wantErr bool
+	}{
+		{"valid proofs", func(result *gethclient.AccountResult) {}, false},
+		{"balance", func(result *gethclient.AccountResult) { result.Balance = big.NewInt(1) }, true},
+		{"nonce", func(result *gethclient.AccountResult) { result.Nonce++ }, true},
+		{"storage root", func(result *gethclient.AccountResult) { result.StorageHash = common.HexToHash("0x01") }, true},
+		{"code hash", func(result *gethclient.AccountResult) { result.CodeHash = common.HexToHash("0x01") }, true},
+		{"account proof node", func(result *gethclient.AccountResult) {
+			result.AccountProof[0] = tamperNode(result.AccountProof[0])
+		}, true},
+		{"missing account proof", func(result *gethclient.AccountResult) { result.AccountProof = nil }, true},
+		{"slot value", func(result *gethclient.AccountResult) { result.StorageProof[0].Value = big.NewInt(43) }, true},
+		{"storage proof node", func(result *gethclient.AccountResult) {
+			proof := result.StorageProof[0].Proof
+			proof[len(proof)-1] = tamperNode(proof[len(proof)-1])
+		}, true},
+		{"missing storage proof", func(result *gethclient.AccountResult) { result.StorageProof = nil }, true},
+		{"other account", func(result *gethclient.AccountResult) { result.Address = common.HexToAddress("0x02") }, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := state.result(t)
+			test.tamper(result)
+
+			p := NewProver(1, fakeHeaders{header: state.header}, fakeProofs{result: result})
+			proven, err := p.ProveAccount(context.Background(), state.address, []string{state.slot.Hex()}, "7")
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("tampered proof was accepted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !proven.Exists || proven.Balance != "1000" || proven.Nonce != 3 || proven.BlockHash != state.header.Hash().Hex() {
+				t.Fatalf("proven account = %+v", proven)
+			}
+			if len(proven.Storage) != 1 || proven.Storage[0].Value != common.BigToHash(big.NewInt(42)).Hex() {
+				t.Fatalf("proven storage = %+v", proven.Storage)
+			}
+		})
+	}
+}
+
+func TestProveAbsentAccount(t *testing.T) {
+	state := newTestState(t)
+	absent := common.HexToAddress("0x000000000000000000000000000000000000dead")
+
+	proof, err := proveKey(state.accounts, absent.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := &gethclient.AccountResult{Address: absent, AccountProof: proof, Balance: big.NewInt(0)}
+	exists, err := verifyAccount(state.header.Root, absent, result)
+	if err != nil || exists {
+		t.Fatalf("verifyAccount = %t, %v, want a valid proof of absence", exists, err)
+	}
+
+	// An absent account cannot have a balance
+	result.Balance = big.NewInt(5)
+	if _, err := verifyAccount(state.header.Root, absent, result); err == nil {
+		t.Fatal("proof of absence accepted for an account with a balance")
+	}
+}
+
+func TestProveAccountRejectsForeignHeaders(t *testing.T) {
+	state := newTestState(t)
+
+	p := NewProver(1, fakeHeaders{header: state.header, hash: common.HexToHash("0x01")}, fakeProofs{result: state.result(t)})
+	if _, err := p.ProveAccount(context.Background(), state.address, nil, "7"); err == nil {
+		t.Fatal("header with another hash was accepted")
+	}
+}
+
+type testState struct {
+	address  common.Address
+	slot     common.Hash
+	accounts *trie.Trie
+	storage  *trie.Trie
+	account  types.StateAccount
+	header   *types.Header
+}
+
+// newTestState builds an account with one storage slot next to a few other accounts, so the
+// proofs have more than one node.
+func newTestState(t *testing.T) *testState {
+	t.Helper()
+
+	s := &testState{
+		address:  common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		slot:     common.HexToHash("0x05"),
+		accounts: trie.NewEmpty(triedb.NewDatabase(rawdb.NewMemoryDatabase(), nil)),
+		storage:  trie.NewEmpty(triedb.NewDatabase(rawdb.NewMemoryDatabase(), nil)),
+	}
+
+	value, _ := rlp.EncodeToBytes(big.NewInt(42).Bytes())
+	if err := s.storage.Update(crypto.Keccak256(s.slot.Bytes()), value); err != nil {
+		t.Fatal(err)
+	}
+	for i := int64(10); i < 20; i++ {
+		other, _ := rlp.EncodeToBytes(big.NewInt(i).Bytes())
+		if err := s.storage.Update(crypto.Keccak256(common.BigToHash(big.NewInt(i)).Bytes()), other); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.account = types.StateAccount{
+		Nonce:    3,
+		Balance:  uint256.NewInt(1000),
+		Root:     s.storage.Hash(),
+		CodeHash: crypto.Keccak256([]byte{0x60, 0x00}),
+	}
+	addAccount(t, s.accounts, s.address, s.account)
+	for i := int64(2); i < 12; i++ {
+		addAccount(t, s.accounts, common.BigToAddress(big.NewInt(i)), types.StateAccount{
+			Balance:  uint256.NewInt(uint64(i)),
+			Root:     types.EmptyRootHash,
+			CodeHash: types.EmptyCodeHash.Bytes(),
+		})
+	}
+
+	s.header = &types.Header{Number: big.NewInt(7), Root: s.accounts.Hash(), Difficulty: big.NewInt(0)}
+
+	return s
+}
+
+// result is what eth_getProof returns for the account and its slot.
+func (s *testState) result(t *testing.T) *gethclient.AccountResult {
+	t.Helper()
+
+	accountProof, err := proveKey(s.accounts, s.address.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storageProof, err := proveKey(s.storage, s.slot.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &gethclient.AccountResult{
+		Address:      s.address,
+		AccountProof: accountProof,
+		Balance:      s.account.Balance.ToBig(),
+		CodeHash:     common.BytesToHash(s.account.CodeHash),
+		Nonce:        s.account.Nonce,
+		StorageHash:  s.account.Root,
+		StorageProof: []gethclient.StorageResult{{Key: s.slot.Hex(), Value: big.NewInt(42), Proof: storageProof}},
+	}
+}
+
+func addAccount(t *testing.T, accounts *trie.Trie, address common.Address, account types.StateAccount) {
+	t.Helper()
+
+	encoded, err := rlp.EncodeToBytes(&account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := accounts.Update(crypto.Keccak256(address.Bytes()), encoded); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// proveKey returns the proof nodes from the root down, like eth_getProof.
+func proveKey(tr *trie.Trie, key []byte) ([]string, error) {
+	proof := &proofNodes{}
+	if err := tr.Prove(crypto.Keccak256(key), proof); err != nil {
+		return nil, err
+	}
+	return proof.nodes, nil
+}
+
+// proofNodes keeps the nodes in the order the trie writes them.
+type proofNodes struct {
+	nodes []string
+}
+
+func (p *proofNodes) Put(key []byte, value []byte) error {
+	p.nodes = append(p.nodes, hexutil.Encode(value))
+	return nil
+}
+
+func (p *proofNodes) Delete(key []byte) error {
+	return nil
+}
+
+func tamperNode(node string) string {
+	encoded := hexutil.MustDecode(node)
+	encoded[len(encoded)-1] ^= 0x01
+	return hexutil.Encode(encoded)
+}
+
+type fakeHeaders struct {
+	header *types.Header
+	hash   common.Hash
+}
+
+func (h fakeHeaders) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	encoded, err := json.Marshal(h.header)
+	if err != nil {
+		return err
+	}
+
+	if h.hash != (common.Hash{}) {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(encoded, &fields); err != nil {
+			return err
+		}
+		fields["hash"] = h.hash.Hex()
+		if encoded, err = json.Marshal(fields); err != nil {
+			return err
+		}
+	}
+
+	*result.(*json.RawMessage) = encoded
+	return nil
+}
+
+type fakeProofs struct {
+	result *gethclient.AccountResult
+}
+
+func (p fakeProofs) GetProof(ctx context.Context, account common.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	return p.result, nil
+}
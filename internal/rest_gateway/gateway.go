@@ -0,0 +1,386 @@
+package restgateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	rpcservices "github.com/avalkov/eth-node-interaction/internal/rpc_services"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func NewGateway(authenticator authenticator, chains chains, backends map[uint64]rpcservices.ChainBackend) *gateway {
+	g := &gateway{
+		authenticator: authenticator,
+		chains:        chains,
+		backends:      backends,
+	}
+
+	g.routes = []route{
+		{
+			method:  http.MethodPost,
+			path:    "/v1/auth/token",
+			summary: "Exchanges credentials for a bearer token",
+			body:    rpcservices.AuthenticateRequest{},
+			reply:   rpcservices.AuthenticateReply{},
+			handle:  g.createToken,
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/v1/transactions/{hash}",
+			summary: "Returns a transaction, with a token it is added to the transactions of the user",
+			auth:    optionalAuth,
+			params:  []param{{"hash", "path", true, "Transaction hash"}, chainParam},
+			reply:   model.Transaction{},
+			missing: "Unknown transaction",
+			handle:  g.getTransaction,
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/v1/transactions",
+			summary: "Returns the transactions, with a token they are added to the transactions of the user",
+			auth:    optionalAuth,
+			params:  []param{{"hashes", "query", true, "Comma separated transaction hashes"}, chainParam},
+			reply:   rpcservices.GetEthTransactionsReply{},
+			missing: "One of the transactions is unknown",
+			handle:  g.getTransactions,
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/v1/me/transactions",
+			summary: "Returns a page of the transactions requested with the token, ordered by block number and index",
+			auth:    requiredAuth,
+			params: []param{
+				chainParam,
+				{"status", "query", false, "Status name or number"},
+				{"fromBlock", "query", false, "First block number"},
+				{"toBlock", "query", false, "Last block number"},
+				{"from", "query", false, "Sender address"},
+				{"to", "query", false, "Recipient address"},
+				{"contractAddress", "query", false, "Created contract address"},
+				{"hasLogs", "query", false, "true or false"},
+				{"order", "query", false, "asc (default) or desc"},
+				{"limit", "query", false, "Page size"},
+				{"cursor", "query", false, "nextCursor of the previous page"},
+			},
+			reply:  rpcservices.ListTransactionsReply{},
+			handle: g.getMyTransactions,
+		},
+	}
+
+	g.openApi = g.openApiDocument()
+
+	return g
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && r.URL.Path == openApiPath {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(g.openApi)
+		return
+	}
+
+	pathMatched := false
+	for _, route := range g.routes {
+		pathParams, ok := route.match(r.URL.Path)
+		if !ok {
+			continue
+		}
+		pathMatched = true
+
+		if route.method != r.Method {
+			continue
+		}
+
+		token := ""
+		if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+			token = strings.TrimPrefix(header, "Bearer ")
+		}
+
+		var owner *model.TxOwner
+		if route.auth != noAuth && token != "" {
+			username, err := g.authenticator.Username(token)
+			if err != nil {
+				writeError(w, http.StatusUnauthorized, errors.New("invalid token"))
+				return
+			}
+			owner = &model.TxOwner{Token: token, Username: username}
+		}
+
+		if route.auth == requiredAuth && owner == nil {
+			writeError(w, http.StatusUnauthorized, errors.New("missing token"))
+			return
+		}
+
+		reply, err := route.handle(r, pathParams, owner)
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+
+		writeJson(w, http.StatusOK, reply)
+		return
+	}
+
+	if pathMatched {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	writeError(w, http.StatusNotFound, errors.New("not found"))
+}
+
+func (g *gateway) createToken(r *http.Request, _ map[string]string, _ *model.TxOwner) (interface{}, error) {
+	var request rpcservices.AuthenticateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, invalidRequest(fmt.Errorf("invalid request body: %s", err))
+	}
+
+	token, err := g.authenticator.Authenticate(r.Context(), request.Username, request.Password)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errUnauthorized, err)
+	}
+
+	return rpcservices.AuthenticateReply{Token: token}, nil
+}
+
+func (g *gateway) getTransaction(r *http.Request, pathParams map[string]string, owner *model.TxOwner) (interface{}, error) {
+	transactions, err := g.fetch(r.Context(), r.URL.Query().Get("chain"), []string{pathParams["hash"]}, owner)
+	if err != nil {
+		return nil, err
+	}
+	return transactions[0], nil
+}
+
+func (g *gateway) getTransactions(r *http.Request, _ map[string]string, owner *model.TxOwner) (interface{}, error) {
+	hashes := []string{}
+	for _, hash := range strings.Split(r.URL.Query().Get("hashes"), ",") {
+		if hash = strings.TrimSpace(hash); hash != "" {
+			hashes = append(hashes, hash)
+		}
+	}
+
+	if len(hashes) == 0 {
+		return nil, invalidRequest(errors.New("missing tx hashes"))
+	}
+
+	transactions, err := g.fetch(r.Context(), r.URL.Query().Get("chain"), hashes, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return rpcservices.GetEthTransactionsReply{Transactions: transactions}, nil
+}
+
+func (g *gateway) getMyTransactions(r *http.Request, _ map[string]string, owner *model.TxOwner) (interface{}, error) {
+	query := r.URL.Query()
+
+	backend, err := g.backend(query.Get("chain"))
+	if err != nil {
+		return nil, err
+	}
+
+	request := rpcservices.ListTransactionsRequest{
+		Status:          query.Get("status"),
+		From:            query.Get("from"),
+		To:              query.Get("to"),
+		ContractAddress: query.Get("contractAddress"),
+		Order:           query.Get("order"),
+		Cursor:          query.Get("cursor"),
+	}
+
+	if request.FromBlock, err = uintParam(query.Get("fromBlock"), "fromBlock"); err != nil {
+		return nil, invalidRequest(err)
+	}
+	if request.ToBlock, err = uintParam(query.Get("toBlock"), "toBlock"); err != nil {
+		return nil, invalidRequest(err)
+	}
+
+	if value := query.Get("hasLogs"); value != "" {
+		hasLogs, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, invalidRequest(fmt.Errorf("invalid hasLogs (%s)", value))
+		}
+		request.HasLogs = &hasLogs
+	}
+
+	if value := query.Get("limit"); value != "" {
+		if request.Limit, err = strconv.Atoi(value); err != nil {
+			return nil, invalidRequest(fmt.Errorf("invalid limit (%s)", value))
+		}
+	}
+
+	return backend.ListTransactions(r.Context(), &owner.Token, request)
+}
+
+// fetch keeps the order of the hashes, the fetcher returns transactions as they arrive.
+func (g *gateway) fetch(ctx context.Context, chain string, hashes []string, owner *model.TxOwner) ([]model.Transaction, error) {
+	backend, err := g.backend(chain)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, hash := range hashes {
+		if len(hash) != 2+2*common.HashLength || !strings.HasPrefix(hash, "0x") {
+			return nil, invalidRequest(fmt.Errorf("invalid hash (%s)", hash))
+		}
+		hashes[i] = common.HexToHash(hash).Hex()
+	}
+
+	fetched, err := backend.TxFetcher.FetchTx(ctx, owner, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	byHash := make(map[string]model.Transaction)
+	for _, tx := range fetched {
+		byHash[tx.TransactionHash] = tx
+	}
+
+	transactions := []model.Transaction{}
+	for _, hash := range hashes {
+		transactions = append(transactions, byHash[hash])
+	}
+
+	return backend.WithNames(ctx, transactions), nil
+}
+
+func (g *gateway) backend(chain string) (rpcservices.ChainBackend, error) {
+	chainID, err := g.chains.ChainID(chain)
+	if err != nil {
+		return rpcservices.ChainBackend{}, invalidRequest(err)
+	}
+
+	backend, ok := g.backends[chainID]
+	if !ok {
+		return rpcservices.ChainBackend{}, invalidRequest(fmt.Errorf("chain (%d) is not served", chainID))
+	}
+
+	return backend, nil
+}
+
+// match compares the path segment by segment, {name} segments match any value.
+func (rt route) match(path string) (map[string]string, bool) {
+	patternSegments := strings.Split(strings.Trim(rt.path, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternSegments) != len(pathSegments) {
+		return nil, false
+	}
+
+	pathParams := make(map[string]string)
+	for i, segment := range patternSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			pathParams[strings.Trim(segment, "{}")] = pathSegments[i]
+			continue
+		}
+		if segment != pathSegments[i] {
+			return nil, false
+		}
+	}
+
+	return pathParams, true
+}
+
+func uintParam(value, name string) (*uint64, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s (%s)", name, value)
+	}
+
+	return &number, nil
+}
+
+// statusOf maps unknown transactions to 404 and failures of the node or the database to 500,
+// only invalid requests are answered with 400.
+func statusOf(err error) int {
+	var invalid *rpcservices.InvalidRequestError
+	switch {
+	case errors.Is(err, errUnauthorized):
+		return http.StatusUnauthorized
+	case errors.As(err, &invalid):
+		return http.StatusBadRequest
+	case errors.Is(err, ethereum.NotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func invalidRequest(err error) error {
+	return &rpcservices.InvalidRequestError{Err: err}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJson(w, status, errorReply{Error: err.Error()})
+}
+
+func writeJson(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+type authMode int
+
+const (
+	noAuth authMode = iota
+	optionalAuth
+	requiredAuth
+)
+
+const openApiPath = "/v1/openapi.json"
+
+var (
+	errUnauthorized = errors.New("unauthorized")
+
+	chainParam = param{"chain", "query", false, "Chain name or ID, the default chain when missing"}
+)
+
+type route struct {
+	method  string
+	path    string
+	summary string
+	auth    authMode
+	params  []param
+	body    interface{}
+	reply   interface{}
+	missing string
+	handle  func(r *http.Request, pathParams map[string]string, owner *model.TxOwner) (interface{}, error)
+}
+
+type param struct {
+	name        string
+	in          string
+	required    bool
+	description string
+}
+
+type errorReply struct {
+	Error string `json:"error"`
+}
+
+type authenticator interface {
+	Authenticate(ctx context.Context, username, password string) (string, error)
+	Username(token string) (string, error)
+}
+
+type chains interface {
+	ChainID(selector string) (uint64, error)
+}
+
+type gateway struct {
+	authenticator authenticator
+	chains        chains
+	backends      map[uint64]rpcservices.ChainBackend
+	routes        []route
+	openApi       []byte
+}
@@ -0,0 +1,113 @@
+package restgateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	jsonschema "github.com/avalkov/eth-node-interaction/internal/json_schema"
+)
+
+// openApiDocument describes the routes, the schemas are generated from the request and reply types.
+func (g *gateway) openApiDocument() []byte {
+	generator := jsonschema.NewGenerator()
+	errorSchema := generator.Schema(errorReply{})
+
+	paths := map[string]map[string]interface{}{}
+	for _, route := range g.routes {
+		parameters := []interface{}{}
+		for _, p := range route.params {
+			parameters = append(parameters, map[string]interface{}{
+				"name":        p.name,
+				"in":          p.in,
+				"required":    p.required,
+				"description": p.description,
+				"schema":      jsonschema.Schema{"type": "string"},
+			})
+		}
+
+		responses := map[string]interface{}{
+			"200": jsonContent("Success", generator.Schema(route.reply)),
+			"400": jsonContent("Invalid request", errorSchema),
+			"500": jsonContent("Node or database failure", errorSchema),
+		}
+		if route.missing != "" {
+			responses["404"] = jsonContent(route.missing, errorSchema)
+		}
+
+		operation := map[string]interface{}{
+			"operationId": operationID(route),
+			"summary":     route.summary,
+			"parameters":  parameters,
+			"responses":   responses,
+		}
+
+		if route.body != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": generator.Schema(route.body)}},
+			}
+		}
+
+		switch route.auth {
+		case optionalAuth:
+			operation["security"] = []interface{}{map[string]interface{}{}, map[string]interface{}{"bearer": []string{}}}
+			responses["401"] = jsonContent("Invalid token", errorSchema)
+		case requiredAuth:
+			operation["security"] = []interface{}{map[string]interface{}{"bearer": []string{}}}
+			responses["401"] = jsonContent("Missing or invalid token", errorSchema)
+		}
+
+		if route.method == http.MethodPost && route.auth == noAuth {
+			responses["401"] = jsonContent("Invalid credentials", errorSchema)
+		}
+
+		if paths[route.path] == nil {
+			paths[route.path] = map[string]interface{}{}
+		}
+		paths[route.path][strings.ToLower(route.method)] = operation
+	}
+
+	document, err := json.MarshalIndent(map[string]interface{}{
+		"openapi": "3.1.0",
+		"info": map[string]interface{}{
+			"title":   "Lime REST gateway",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": generator.Components(),
+			"securitySchemes": map[string]interface{}{
+				"bearer": map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	return document
+}
+
+func jsonContent(description string, schema jsonschema.Schema) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}},
+	}
+}
+
+// operationID turns "GET /v1/me/transactions" into "getMeTransactions".
+func operationID(route route) string {
+	id := strings.ToLower(route.method)
+	for _, segment := range strings.Split(strings.TrimPrefix(route.path, "/v1/"), "/") {
+		if strings.HasPrefix(segment, "{") {
+			segment = "by-" + strings.Trim(segment, "{}")
+		}
+		for _, word := range strings.Split(segment, "-") {
+			if word != "" {
+				id += strings.ToUpper(word[:1]) + word[1:]
+			}
+		}
+	}
+	return id
+}
@@ -1,9 +1,11 @@
 package rpccodecs
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"reflect"
 	"strings"
@@ -32,39 +34,67 @@ type CustomRequestsCodecRequest struct {
 	*CodecRequest
 }
 
+// Method maps "service_method" to "Service.Method". Dotted names such as "rpc.discover" are
+// accepted too.
 func (c *CustomRequestsCodecRequest) Method() (string, error) {
 	m, err := c.CodecRequest.Method()
 	if len(m) > 1 && err == nil {
-		parts := strings.Split(m, "_")
-		if len(parts) != 2 {
-			return "", fmt.Errorf("invalid method: %s", m)
-		}
-		service, method := parts[0], parts[1]
-		return capitalize(service) + "." + capitalize(method), nil
+		return serviceMethod(m)
 	}
 	return m, err
 }
 
+func serviceMethod(name string) (string, error) {
+	separator := "_"
+	if !strings.Contains(name, separator) {
+		separator = "."
+	}
+	parts := strings.Split(name, separator)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid method: %s", name)
+	}
+	service, method := parts[0], parts[1]
+	return capitalize(service) + "." + capitalize(method), nil
+}
+
+// MethodName is the name clients call a method of a registered service by.
+func MethodName(service, method string) string {
+	return uncapitalize(service) + "_" + uncapitalize(method)
+}
+
 func capitalize(value string) string {
 	r, n := utf8.DecodeRuneInString(value)
 	return string(unicode.ToUpper(r)) + value[n:]
 }
 
+func uncapitalize(value string) string {
+	r, n := utf8.DecodeRuneInString(value)
+	return string(unicode.ToLower(r)) + value[n:]
+}
+
 var null = json.RawMessage([]byte("null"))
 
+const (
+	version1 = "1.0"
+	version2 = "2.0"
+)
+
 // ----------------------------------------------------------------------------
 // Request and Response
 // ----------------------------------------------------------------------------
 
 // serverRequest represents a JSON-RPC request received by the server.
 type serverRequest struct {
+	// "2.0" for JSON-RPC 2.0, empty or "1.0" for the original protocol.
+	Version string `json:"jsonrpc"`
 	// A String containing the name of the method to be invoked.
 	Method string `json:"method"`
 	// An Array of objects to pass as arguments to the method.
 	Params *json.RawMessage `json:"params"`
 	// The request id. This can be of any type. It is used to match the
-	// response with the request that it is replying to.
-	Id *json.RawMessage `json:"id"`
+	// response with the request that it is replying to. It is absent for
+	// notifications, a null id is a notification only in the original protocol.
+	Id json.RawMessage `json:"id"`
 }
 
 // serverResponse represents a JSON-RPC response returned by the server.
@@ -79,6 +109,14 @@ type serverResponse struct {
 	Id *json.RawMessage `json:"id"`
 }
 
+// serverResponse2 represents a JSON-RPC 2.0 response, it has either a result or an error.
+type serverResponse2 struct {
+	Version string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	Id      json.RawMessage `json:"id"`
+}
+
 // ----------------------------------------------------------------------------
 // Codec
 // ----------------------------------------------------------------------------
@@ -104,12 +142,44 @@ func (c *Codec) NewRequest(r *http.Request) rpc.CodecRequest {
 // newCodecRequest returns a new CodecRequest.
 func newCodecRequest(r *http.Request) rpc.CodecRequest {
 	// Decode the request body and check if RPC method is valid.
-	req := new(serverRequest)
-	err := json.NewDecoder(r.Body).Decode(req)
+	body, err := io.ReadAll(r.Body)
 	r.Body.Close()
+	if err != nil {
+		return &CodecRequest{request: new(serverRequest), err: err}
+	}
+
+	req, err := decodeRequest(body)
+	if req == nil {
+		req = new(serverRequest)
+	}
 	return &CodecRequest{request: req, err: err}
 }
 
+// decodeRequest tells malformed JSON apart from JSON that is not a request. The request is
+// returned with the error when only its fields are invalid.
+func decodeRequest(body []byte) (*serverRequest, error) {
+	req := new(serverRequest)
+	if err := json.Unmarshal(body, req); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, newError(InvalidRequest, "invalid request: %s", err)
+		}
+		return nil, newError(ParseError, "parse error: %s", err)
+	}
+
+	switch req.Version {
+	case "", version1:
+	case version2:
+		if req.Method == "" {
+			return req, newError(InvalidRequest, "invalid request: missing method")
+		}
+	default:
+		return req, newError(InvalidRequest, "unsupported jsonrpc version (%s)", req.Version)
+	}
+
+	return req, nil
+}
+
 // CodecRequest decodes and encodes a single request.
 type CodecRequest struct {
 	request *serverRequest
@@ -128,19 +198,18 @@ func (c *CodecRequest) Method() (string, error) {
 
 // ReadRequest fills the request object for the RPC method.
 func (c *CodecRequest) ReadRequest(args interface{}) error {
-	if c.err == nil {
-		if c.request.Params != nil {
-
-			if reflect.ValueOf(args).Elem().Kind() == reflect.Struct {
-				params := []interface{}{args}
-				c.err = json.Unmarshal(*c.request.Params, &params)
-				return c.err
+	// Without params the method gets the zero value of its args, as rpc.discover is called without them
+	if c.err == nil && c.request.Params != nil {
+		if reflect.ValueOf(args).Elem().Kind() == reflect.Struct {
+			params := []interface{}{args}
+			if err := json.Unmarshal(*c.request.Params, &params); err != nil {
+				c.err = newError(InvalidParams, "%s", err)
 			}
+			return c.err
+		}
 
-			c.err = json.Unmarshal(*c.request.Params, &args)
-
-		} else {
-			c.err = errors.New("rpc: method request ill-formed: missing params field")
+		if err := json.Unmarshal(*c.request.Params, &args); err != nil {
+			c.err = newError(InvalidParams, "%s", err)
 		}
 	}
 	return c.err
@@ -154,10 +223,36 @@ func (c *CodecRequest) WriteResponse(w http.ResponseWriter, reply interface{}, m
 	if c.err != nil {
 		return c.err
 	}
+	if c.request.Version == version2 {
+		c.err = writeResponse2(w, c.request.Id, reply, methodErr)
+		return c.err
+	}
+	c.err = writeResponse1(w, c.request.Id, reply, methodErr)
+	return c.err
+}
+
+// EncodeResponse encodes the answer to a request served outside of the rpc server, in the protocol
+// version of the request. Notifications get no answer.
+func EncodeResponse(version string, id json.RawMessage, reply interface{}, methodErr error) []byte {
+	response := newResponseBuffer()
+	if version == version2 {
+		writeResponse2(response, id, reply, methodErr)
+	} else {
+		writeResponse1(response, id, reply, methodErr)
+	}
+	return bytes.TrimSpace(response.body.Bytes())
+}
+
+// writeResponse1 answers in the original protocol, where errors are strings.
+func writeResponse1(w http.ResponseWriter, id json.RawMessage, reply interface{}, methodErr error) error {
+	// Id is null for notifications and they don't have a response.
+	if len(id) == 0 || bytes.Equal(id, null) {
+		return nil
+	}
 	res := &serverResponse{
 		Result: reply,
 		Error:  &null,
-		Id:     c.request.Id,
+		Id:     &id,
 	}
 	if methodErr != nil {
 		// Propagate error message as string.
@@ -166,13 +261,25 @@ func (c *CodecRequest) WriteResponse(w http.ResponseWriter, reply interface{}, m
 		// http://json-rpc.org/wiki/specification#a1.2Response
 		res.Result = &null
 	}
-	if c.request.Id == nil {
-		// Id is null for notifications and they don't have a response.
-		res.Id = &null
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(res)
+}
+
+// writeResponse2 answers with the result or the error of the method. Notifications are not answered,
+// even when they fail.
+func writeResponse2(w http.ResponseWriter, id json.RawMessage, reply interface{}, methodErr error) error {
+	if len(id) == 0 {
+		return nil
+	}
+	res := &serverResponse2{
+		Version: version2,
+		Id:      id,
+	}
+	if methodErr != nil {
+		res.Error = toError(methodErr)
 	} else {
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		encoder := json.NewEncoder(w)
-		c.err = encoder.Encode(res)
+		res.Result = reply
 	}
-	return c.err
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(res)
 }
@@ -0,0 +1,41 @@
+package rpccodecs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error is the error object of JSON-RPC 2.0 responses. Methods return it to answer with a specific
+// code or data, other errors are answered as ServerError.
+type Error struct {
+	Code    ErrorCode   `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(code ErrorCode, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func toError(err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	return &Error{Code: ServerError, Message: err.Error()}
+}
+
+type ErrorCode int
+
+const (
+	ParseError     ErrorCode = -32700
+	InvalidRequest ErrorCode = -32600
+	MethodNotFound ErrorCode = -32601
+	InvalidParams  ErrorCode = -32602
+	InternalError  ErrorCode = -32603
+	ServerError    ErrorCode = -32000
+)
@@ -0,0 +1,228 @@
+package rpccodecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// NewHandler serves the JSON-RPC requests of the server, whose methods are named as the
+// CustomRequestsCodec exposes them. The server answers requests it cannot dispatch with plain text
+// errors, JSON-RPC 2.0 clients get error objects with the standard codes instead. Requests of the
+// original protocol are served as they are. Batches of up to maxBatchSize calls run concurrency
+// calls at a time.
+func NewHandler(server server, maxBatchSize, concurrency int) *handler {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &handler{
+		server:       server,
+		maxBatchSize: maxBatchSize,
+		concurrency:  concurrency,
+	}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.server.ServeHTTP(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		h.serveBatch(w, r, body)
+		return
+	}
+
+	_, response := h.serve(r, body)
+	response.copyTo(w)
+}
+
+// serveBatch runs the calls concurrently, each one authenticated by the token of its own params.
+// Responses keep the order of the calls and carry their ids, notifications are left out.
+func (h *handler) serveBatch(w http.ResponseWriter, r *http.Request, body []byte) {
+	var calls []json.RawMessage
+	if err := json.Unmarshal(body, &calls); err != nil {
+		writeResponse2(w, null, nil, newError(ParseError, "parse error: %s", err))
+		return
+	}
+
+	if len(calls) == 0 {
+		writeResponse2(w, null, nil, newError(InvalidRequest, "invalid request: empty batch"))
+		return
+	}
+
+	if len(calls) > h.maxBatchSize {
+		writeResponse2(w, null, nil, newError(InvalidRequest, "batch of %d calls exceeds the maximum of %d", len(calls), h.maxBatchSize))
+		return
+	}
+
+	responses := make([]json.RawMessage, len(calls))
+	slots := make(chan struct{}, h.concurrency)
+
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		slots <- struct{}{}
+
+		go func(i int, call json.RawMessage) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer func() {
+				// The server does not recover panics of methods, net/http only does it for its own goroutines
+				if recovered := recover(); recovered != nil {
+					log.Println(fmt.Errorf("rpc batch call panicked: %v", recovered))
+					responses[i], _ = json.Marshal(&serverResponse2{
+						Version: version2,
+						Error:   newError(InternalError, "internal error"),
+						Id:      null,
+					})
+				}
+			}()
+
+			responses[i] = h.batchResponse(r, call)
+		}(i, call)
+	}
+	wg.Wait()
+
+	answered := []json.RawMessage{}
+	for _, response := range responses {
+		if len(response) > 0 {
+			answered = append(answered, response)
+		}
+	}
+
+	// A batch of notifications is not answered
+	if len(answered) == 0 {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(answered); err != nil {
+		log.Println(fmt.Errorf("failed to write rpc batch response: %s", err))
+	}
+}
+
+// batchResponse answers the plain text errors of calls of the original protocol in its own format,
+// as they cannot be part of the batch response otherwise.
+func (h *handler) batchResponse(r *http.Request, call json.RawMessage) json.RawMessage {
+	request, response := h.serve(r, call)
+	if response.status == http.StatusOK {
+		return bytes.TrimSpace(response.body.Bytes())
+	}
+
+	if len(request.Id) == 0 || bytes.Equal(request.Id, null) {
+		return nil
+	}
+
+	message, err := json.Marshal(&serverResponse{
+		Result: &null,
+		Error:  strings.TrimSpace(response.body.String()),
+		Id:     &request.Id,
+	})
+	if err != nil {
+		return nil
+	}
+
+	return message
+}
+
+// serve answers a single request. The request is empty when it could not be decoded.
+func (h *handler) serve(r *http.Request, body []byte) (*serverRequest, *responseBuffer) {
+	response := newResponseBuffer()
+
+	// Malformed requests are answered as JSON-RPC 2.0, with a null id unless it could be read
+	request, err := decodeRequest(body)
+	if err != nil {
+		if request == nil {
+			request = &serverRequest{Version: version2, Id: null}
+		}
+		if len(request.Id) == 0 {
+			request.Id = null
+		}
+		writeResponse2(response, request.Id, nil, err)
+		return request, response
+	}
+
+	if request.Version != version2 {
+		h.server.ServeHTTP(response, withBody(r, body))
+		return request, response
+	}
+
+	method, err := serviceMethod(request.Method)
+	if err != nil || !h.server.HasMethod(method) {
+		writeResponse2(response, request.Id, nil, newError(MethodNotFound, "method (%s) not found", request.Method))
+		return request, response
+	}
+
+	h.server.ServeHTTP(response, withBody(r, body))
+
+	// Once the method is found, the server only rejects params it cannot decode
+	if response.status == http.StatusBadRequest {
+		message := strings.TrimSpace(response.body.String())
+		response = newResponseBuffer()
+		writeResponse2(response, request.Id, nil, newError(InvalidParams, "%s", message))
+	}
+
+	return request, response
+}
+
+func withBody(r *http.Request, body []byte) *http.Request {
+	r = r.Clone(r.Context())
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	return r
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: make(http.Header), status: http.StatusOK}
+}
+
+func (rb *responseBuffer) Header() http.Header {
+	return rb.header
+}
+
+func (rb *responseBuffer) Write(data []byte) (int, error) {
+	return rb.body.Write(data)
+}
+
+func (rb *responseBuffer) WriteHeader(status int) {
+	rb.status = status
+}
+
+func (rb *responseBuffer) copyTo(w http.ResponseWriter) {
+	for key, values := range rb.header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(rb.status)
+	w.Write(rb.body.Bytes())
+}
+
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+type server interface {
+	http.Handler
+	HasMethod(method string) bool
+}
+
+type handler struct {
+	server       server
+	maxBatchSize int
+	concurrency  int
+}
@@ -0,0 +1,420 @@
+package rpccodecs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/rpc"
+)
+
+func TestParseError(t *testing.T) {
+	response := decodeResponse(t, post(t, newTestHandler(t), `{"jsonrpc":"2.0","method":`))
+
+	assertError(t, response, ParseError)
+	if string(response.Id) != "null" {
+		t.Fatalf("id = %s, want null", response.Id)
+	}
+}
+
+func TestInvalidRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		wantId string
+	}{
+		{"missing method", `{"jsonrpc":"2.0","id":1}`, "1"},
+		{"method is not a string", `{"jsonrpc":"2.0","method":5,"id":1}`, "null"},
+		{"unsupported version", `{"jsonrpc":"3.0","method":"echo_say","id":"a"}`, `"a"`},
+		{"not an object", `"echo_say"`, "null"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := decodeResponse(t, post(t, newTestHandler(t), test.body))
+
+			assertError(t, response, InvalidRequest)
+			if string(response.Id) != test.wantId {
+				t.Fatalf("id = %s, want %s", response.Id, test.wantId)
+			}
+		})
+	}
+}
+
+func TestMethodNotFound(t *testing.T) {
+	for _, method := range []string{"echo_missing", "missing_say", "echo"} {
+		t.Run(method, func(t *testing.T) {
+			response := decodeResponse(t, post(t, newTestHandler(t), `{"jsonrpc":"2.0","method":"`+method+`","params":[],"id":7}`))
+
+			assertError(t, response, MethodNotFound)
+			if string(response.Id) != "7" {
+				t.Fatalf("id = %s, want 7", response.Id)
+			}
+		})
+	}
+}
+
+func TestInvalidParams(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"wrong field type", `{"jsonrpc":"2.0","method":"echo_say","params":[{"message":5}],"id":1}`},
+		{"object instead of array", `{"jsonrpc":"2.0","method":"echo_say","params":{"message":"hi"},"id":1}`},
+		{"positional params of the wrong type", `{"jsonrpc":"2.0","method":"echo_join","params":[1,2],"id":1}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assertError(t, decodeResponse(t, post(t, newTestHandler(t), test.body)), InvalidParams)
+		})
+	}
+}
+
+func TestMethodErrors(t *testing.T) {
+	h := newTestHandler(t)
+
+	response := decodeResponse(t, post(t, h, `{"jsonrpc":"2.0","method":"echo_say","params":[{}],"id":1}`))
+	assertError(t, response, ServerError)
+	if response.Error.Message != "missing message" {
+		t.Fatalf("message = %s", response.Error.Message)
+	}
+
+	response = decodeResponse(t, post(t, h, `{"jsonrpc":"2.0","method":"echo_fail","params":[],"id":1}`))
+	assertError(t, response, ErrorCode(-32001))
+	if response.Error.Data != "details" {
+		t.Fatalf("data = %v", response.Error.Data)
+	}
+}
+
+func TestResults(t *testing.T) {
+	h := newTestHandler(t)
+
+	response := decodeResponse(t, post(t, h, `{"jsonrpc":"2.0","method":"echo_say","params":[{"message":"hi"}],"id":"x"}`))
+	if response.Version != version2 || response.Error != nil || string(response.Result) != `{"message":"hi"}` || string(response.Id) != `"x"` {
+		t.Fatalf("response = %+v", response)
+	}
+
+	// The original protocol keeps a null error next to the result
+	body := post(t, h, `{"method":"echo_join","params":["a","b"],"id":2}`)
+	if body != `{"result":{"message":"a b"},"error":null,"id":2}` {
+		t.Fatalf("response = %s", body)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"2.0 without id", `{"jsonrpc":"2.0","method":"echo_say","params":[{"message":"hi"}]}`},
+		{"2.0 failing without id", `{"jsonrpc":"2.0","method":"echo_fail","params":[]}`},
+		{"original protocol with null id", `{"method":"echo_join","params":["a"],"id":null}`},
+		{"original protocol without id", `{"method":"echo_join","params":["a"]}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if body := post(t, newTestHandler(t), test.body); body != "" {
+				t.Fatalf("notification answered with %s", body)
+			}
+		})
+	}
+
+	// A null id is a regular id in JSON-RPC 2.0
+	response := decodeResponse(t, post(t, newTestHandler(t), `{"jsonrpc":"2.0","method":"echo_say","params":[{"message":"hi"}],"id":null}`))
+	if string(response.Result) != `{"message":"hi"}` || string(response.Id) != "null" {
+		t.Fatalf("response = %+v", response)
+	}
+}
+
+func TestEncodeResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		id      string
+		reply   interface{}
+		err     error
+		want    string
+	}{
+		{"2.0 result", version2, "1", true, nil, `{"jsonrpc":"2.0","result":true,"id":1}`},
+		{"2.0 error", version2, "1", nil, errors.New("failed"), `{"jsonrpc":"2.0","error":{"code":-32000,"message":"failed"},"id":1}`},
+		{"2.0 notification", version2, "", true, nil, ``},
+		{"original result", "", "1", true, nil, `{"result":true,"error":null,"id":1}`},
+		{"original error", version1, "1", nil, errors.New("failed"), `{"result":null,"error":"failed","id":1}`},
+		{"original notification", "", "null", true, nil, ``},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := string(EncodeResponse(test.version, json.RawMessage(test.id), test.reply, test.err)); got != test.want {
+				t.Fatalf("response = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBatchMatchesResponsesToCalls(t *testing.T) {
+	body := post(t, newTestHandler(t), `[
+		{"jsonrpc":"2.0","method":"echo_say","params":[{"message":"first"}],"id":1},
+		{"jsonrpc":"2.0","method":"echo_missing","params":[],"id":"second"},
+		{"jsonrpc":"2.0","method":"echo_say","params":[{"message":5}],"id":3},
+		{"method":"echo_join","params":["fourth"],"id":4},
+		5
+	]`)
+
+	var responses []json.RawMessage
+	if err := json.Unmarshal([]byte(body), &responses); err != nil {
+		t.Fatalf("invalid batch response %q: %s", body, err)
+	}
+	if len(responses) != 5 {
+		t.Fatalf("%d responses, want 5", len(responses))
+	}
+
+	first := decodeResponse(t, string(responses[0]))
+	if string(first.Id) != "1" || string(first.Result) != `{"message":"first"}` {
+		t.Fatalf("first response = %s", responses[0])
+	}
+
+	second := decodeResponse(t, string(responses[1]))
+	assertError(t, second, MethodNotFound)
+	if string(second.Id) != `"second"` {
+		t.Fatalf("second id = %s", second.Id)
+	}
+
+	third := decodeResponse(t, string(responses[2]))
+	assertError(t, third, InvalidParams)
+	if string(third.Id) != "3" {
+		t.Fatalf("third id = %s", third.Id)
+	}
+
+	// Calls of the original protocol are answered in it
+	if string(responses[3]) != `{"result":{"message":"fourth"},"error":null,"id":4}` {
+		t.Fatalf("fourth response = %s", responses[3])
+	}
+
+	assertError(t, decodeResponse(t, string(responses[4])), InvalidRequest)
+}
+
+func TestBatchLeavesOutNotifications(t *testing.T) {
+	h := newTestHandler(t)
+
+	body := post(t, h, `[
+		{"jsonrpc":"2.0","method":"echo_say","params":[{"message":"notification"}]},
+		{"jsonrpc":"2.0","method":"echo_say","params":[{"message":"call"}],"id":2},
+		{"method":"echo_join","params":["notification"],"id":null}
+	]`)
+
+	var responses []testResponse
+	if err := json.Unmarshal([]byte(body), &responses); err != nil {
+		t.Fatalf("invalid batch response %q: %s", body, err)
+	}
+	if len(responses) != 1 || string(responses[0].Id) != "2" {
+		t.Fatalf("responses = %s, want only the call with id 2", body)
+	}
+
+	// A batch of notifications is not answered at all
+	body = post(t, h, `[
+		{"jsonrpc":"2.0","method":"echo_say","params":[{"message":"a"}]},
+		{"jsonrpc":"2.0","method":"echo_fail","params":[]}
+	]`)
+	if body != "" {
+		t.Fatalf("batch of notifications answered with %s", body)
+	}
+}
+
+func TestBatchRejections(t *testing.T) {
+	calls := make([]string, 11)
+	for i := range calls {
+		calls[i] = `{"jsonrpc":"2.0","method":"echo_join","params":["a"],"id":1}`
+	}
+
+	tests := []struct {
+		name         string
+		maxBatchSize int
+		body         string
+		code         ErrorCode
+		message      string
+	}{
+		{"too many calls", 10, "[" + strings.Join(calls, ",") + "]", InvalidRequest, "batch of 11 calls exceeds the maximum of 10"},
+		{"empty batch", 10, "[]", InvalidRequest, "invalid request: empty batch"},
+		{"malformed batch", 10, `[{"jsonrpc":"2.0"`, ParseError, ""},
+		{"exactly the maximum", 11, "[" + strings.Join(calls, ",") + "]", 0, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body := post(t, newBatchHandler(t, &Echo{}, test.maxBatchSize, 4), test.body)
+
+			if test.code == 0 {
+				var responses []testResponse
+				if err := json.Unmarshal([]byte(body), &responses); err != nil || len(responses) != len(calls) {
+					t.Fatalf("response = %s, want %d results", body, len(calls))
+				}
+				return
+			}
+
+			response := decodeResponse(t, body)
+			assertError(t, response, test.code)
+			if test.message != "" && response.Error.Message != test.message {
+				t.Fatalf("message = %s, want %s", response.Error.Message, test.message)
+			}
+			if string(response.Id) != "null" {
+				t.Fatalf("id = %s, want null", response.Id)
+			}
+		})
+	}
+}
+
+func TestBatchBoundsConcurrency(t *testing.T) {
+	tests := []struct {
+		concurrency int
+		calls       int
+	}{
+		{1, 4},
+		{3, 9},
+		{8, 4},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%d at a time", test.concurrency), func(t *testing.T) {
+			echo := &Echo{}
+			calls := make([]string, test.calls)
+			for i := range calls {
+				calls[i] = fmt.Sprintf(`{"jsonrpc":"2.0","method":"echo_slow","params":["%d"],"id":%d}`, i, i)
+			}
+
+			body := post(t, newBatchHandler(t, echo, 100, test.concurrency), "["+strings.Join(calls, ",")+"]")
+
+			var responses []testResponse
+			if err := json.Unmarshal([]byte(body), &responses); err != nil || len(responses) != test.calls {
+				t.Fatalf("response = %s, want %d results", body, test.calls)
+			}
+			for i, response := range responses {
+				if string(response.Id) != fmt.Sprint(i) || string(response.Result) != fmt.Sprintf(`{"message":"%d"}`, i) {
+					t.Fatalf("response %d = %+v", i, response)
+				}
+			}
+
+			if maxActive := atomic.LoadInt32(&echo.maxActive); int(maxActive) > test.concurrency {
+				t.Fatalf("%d calls ran at the same time, want at most %d", maxActive, test.concurrency)
+			}
+		})
+	}
+}
+
+func newTestHandler(t *testing.T) *handler {
+	t.Helper()
+	return newBatchHandler(t, &Echo{}, 10, 4)
+}
+
+func newBatchHandler(t *testing.T, echo *Echo, maxBatchSize, concurrency int) *handler {
+	t.Helper()
+
+	server := rpc.NewServer()
+	server.RegisterCodec(NewCustomRequestsCodec(), "application/json")
+	if err := server.RegisterService(echo, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewHandler(server, maxBatchSize, concurrency)
+}
+
+func post(t *testing.T, h http.Handler, body string) string {
+	t.Helper()
+
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, request)
+
+	return strings.TrimSpace(recorder.Body.String())
+}
+
+func decodeResponse(t *testing.T, body string) testResponse {
+	t.Helper()
+
+	var response testResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatalf("invalid response %q: %s", body, err)
+	}
+	if response.Version != version2 {
+		t.Fatalf("response %s is not JSON-RPC 2.0", body)
+	}
+
+	return response
+}
+
+func assertError(t *testing.T, response testResponse, code ErrorCode) {
+	t.Helper()
+
+	if response.Error == nil {
+		t.Fatalf("response = %+v, want error %d", response, code)
+	}
+	if response.Error.Code != code {
+		t.Fatalf("code = %d (%s), want %d", response.Error.Code, response.Error.Message, code)
+	}
+	if response.Result != nil {
+		t.Fatalf("error response with result %s", response.Result)
+	}
+}
+
+type testResponse struct {
+	Version string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result"`
+	Error   *Error          `json:"error"`
+	Id      json.RawMessage `json:"id"`
+}
+
+type Echo struct {
+	active    int32
+	maxActive int32
+}
+
+type EchoRequest struct {
+	Message string `json:"message"`
+}
+
+type EchoReply struct {
+	Message string `json:"message"`
+}
+
+func (e *Echo) Say(r *http.Request, request *EchoRequest, reply *EchoReply) error {
+	if request.Message == "" {
+		return errors.New("missing message")
+	}
+	reply.Message = request.Message
+	return nil
+}
+
+func (e *Echo) Join(r *http.Request, args *[]string, reply *EchoReply) error {
+	reply.Message = strings.Join(*args, " ")
+	return nil
+}
+
+// Slow records how many calls run at the same time.
+func (e *Echo) Slow(r *http.Request, args *[]string, reply *EchoReply) error {
+	active := atomic.AddInt32(&e.active, 1)
+	defer atomic.AddInt32(&e.active, -1)
+
+	for {
+		maxActive := atomic.LoadInt32(&e.maxActive)
+		if active <= maxActive || atomic.CompareAndSwapInt32(&e.maxActive, maxActive, active) {
+			break
+		}
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	reply.Message = strings.Join(*args, " ")
+	return nil
+}
+
+func (e *Echo) Fail(r *http.Request, args *[]string, reply *EchoReply) error {
+	return &Error{Code: -32001, Message: "custom failure", Data: "details"}
+}
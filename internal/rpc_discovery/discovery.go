@@ -0,0 +1,177 @@
+package rpcdiscovery
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	jsonschema "github.com/avalkov/eth-node-interaction/internal/json_schema"
+	rpccodecs "github.com/avalkov/eth-node-interaction/internal/rpc_codecs"
+)
+
+// NewDiscovery collects the methods of the described services into an OpenRPC document. It is
+// registered as the "Rpc" service, so the document is served by rpc.discover.
+func NewDiscovery(title, version string) *discovery {
+	return &discovery{
+		generator: jsonschema.NewGenerator(),
+		document: Document{
+			OpenRPC: openRpcVersion,
+			Info:    Info{Title: title, Version: version},
+			Methods: []Method{},
+		},
+	}
+}
+
+// Describe adds the methods gorilla/rpc registers for the service, with the same name rules.
+// Services with positional params name them through PositionalParams.
+func (d *discovery) Describe(service interface{}, name string) error {
+	value := reflect.ValueOf(service)
+	if name == "" {
+		name = reflect.Indirect(value).Type().Name()
+	}
+
+	named, _ := service.(positionalParams)
+
+	described := 0
+	for i := 0; i < value.Type().NumMethod(); i++ {
+		method := value.Type().Method(i)
+		if !isServiceMethod(method) {
+			continue
+		}
+
+		argsType := method.Type.In(2).Elem()
+		replyType := method.Type.In(3).Elem()
+
+		var names []string
+		if named != nil {
+			names = named.PositionalParams(method.Name)
+		}
+
+		params, err := d.params(argsType, names)
+		if err != nil {
+			return fmt.Errorf("method (%s.%s): %s", name, method.Name, err)
+		}
+
+		d.document.Methods = append(d.document.Methods, Method{
+			Name:           rpccodecs.MethodName(name, method.Name),
+			ParamStructure: "by-position",
+			Params:         params,
+			Result: ContentDescriptor{
+				Name:   "result",
+				Schema: d.generator.Schema(reflect.New(replyType).Elem().Interface()),
+			},
+		})
+		described++
+	}
+
+	if described == 0 {
+		return fmt.Errorf("service (%s) has no methods", name)
+	}
+
+	sort.Slice(d.document.Methods, func(i, j int) bool {
+		return d.document.Methods[i].Name < d.document.Methods[j].Name
+	})
+	d.document.Components.Schemas = d.generator.Components()
+
+	return nil
+}
+
+// Discover returns the OpenRPC document.
+func (d *discovery) Discover(r *http.Request, args *[]string, reply *Document) error {
+	*reply = d.document
+	return nil
+}
+
+// Struct args are sent as the only param, slices param by param.
+func (d *discovery) params(argsType reflect.Type, names []string) ([]ContentDescriptor, error) {
+	if argsType.Kind() == reflect.Struct {
+		return []ContentDescriptor{{
+			Name:     "request",
+			Required: true,
+			Schema:   d.generator.Schema(reflect.New(argsType).Elem().Interface()),
+		}}, nil
+	}
+
+	if argsType.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("unsupported args type %s", argsType)
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("positional params are not named")
+	}
+
+	itemSchema := d.generator.Schema(reflect.New(argsType.Elem()).Elem().Interface())
+
+	params := []ContentDescriptor{}
+	for _, name := range names {
+		params = append(params, ContentDescriptor{
+			Name:     strings.TrimSuffix(name, "?"),
+			Required: !strings.HasSuffix(name, "?"),
+			Schema:   itemSchema,
+		})
+	}
+
+	return params, nil
+}
+
+func isServiceMethod(method reflect.Method) bool {
+	methodType := method.Type
+	if method.PkgPath != "" || methodType.NumIn() != 4 || methodType.NumOut() != 1 {
+		return false
+	}
+
+	return methodType.In(1) == requestType &&
+		methodType.In(2).Kind() == reflect.Ptr &&
+		methodType.In(3).Kind() == reflect.Ptr &&
+		methodType.Out(0) == errorType
+}
+
+const openRpcVersion = "1.2.6"
+
+var (
+	requestType = reflect.TypeOf((*http.Request)(nil))
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+type Document struct {
+	OpenRPC    string     `json:"openrpc"`
+	Info       Info       `json:"info"`
+	Methods    []Method   `json:"methods"`
+	Components Components `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Method struct {
+	Name           string              `json:"name"`
+	ParamStructure string              `json:"paramStructure"`
+	Params         []ContentDescriptor `json:"params"`
+	Result         ContentDescriptor   `json:"result"`
+}
+
+type ContentDescriptor struct {
+	Name     string            `json:"name"`
+	Required bool              `json:"required,omitempty"`
+	Schema   jsonschema.Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas map[string]jsonschema.Schema `json:"schemas"`
+}
+
+type positionalParams interface {
+	PositionalParams(method string) []string
+}
+
+type discovery struct {
+	generator interface {
+		Schema(value interface{}) jsonschema.Schema
+		Components() map[string]jsonschema.Schema
+	}
+	document Document
+}
@@ -1,38 +1,57 @@
 package rpcservices
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/umbracle/fastrlp"
 )
 
-func NewLimeService(txFetcher txFetcher, authenticator authenticator) *Lime {
+func NewLimeService(chains chains, backends map[uint64]ChainBackend, authenticator authenticator, watchlists watchlists,
+	webhooks webhooks) *Lime {
 	return &Lime{
-		txFetcher:     txFetcher,
+		chains:        chains,
+		backends:      backends,
 		authenticator: authenticator,
+		watchlists:    watchlists,
+		webhooks:      webhooks,
 	}
 }
 
+var getEthTransactionsParams = params{"rlpTxHashes", "token?", "chain?"}
+
+// An empty token is treated as missing.
 func (l *Lime) GetEthTransactions(r *http.Request, args *[]string, reply *GetEthTransactionsReply) error {
-	if len((*args)) == 0 {
-		return errors.New("missing tx hashes")
+	if err := getEthTransactionsParams.check(*args); err != nil {
+		return err
 	}
 
-	txs := (*args)[0]
-
-	var token *string
-	if len((*args)) == 2 {
-		token = &(*args)[1]
+	txs := getEthTransactionsParams.value(*args, "rlpTxHashes")
 
-		if err := l.authenticator.VerifyToken(*token); err != nil {
+	var owner *model.TxOwner
+	if token := getEthTransactionsParams.value(*args, "token"); token != "" {
+		username, err := l.authenticator.Username(token)
+		if err != nil {
 			return err
 		}
+
+		owner = &model.TxOwner{Token: token, Username: username}
+	}
+
+	backend, err := l.backend(getEthTransactionsParams.value(*args, "chain"))
+	if err != nil {
+		return err
 	}
 
 	parser := &fastrlp.Parser{}
@@ -53,39 +72,46 @@ func (l *Lime) GetEthTransactions(r *http.Request, args *[]string, reply *GetEth
 		hashes = append(hashes, hash)
 	}
 
-	reply.Transactions, err = l.txFetcher.FetchTx(r.Context(), token, hashes)
-
-	return err
-}
-
-func (l *Lime) GetAllTransactions(r *http.Request, _ *[]string, reply *GetEthTransactionsReply) error {
-	transactions, err := l.txFetcher.FetchAllCachedTx(r.Context())
+	transactions, err := backend.TxFetcher.FetchTx(r.Context(), owner, hashes)
 	if err != nil {
 		return err
 	}
 
-	reply.Transactions = transactions
+	reply.Transactions = backend.WithNames(r.Context(), transactions)
 
 	return nil
 }
 
-func (l *Lime) GetMyTransactions(r *http.Request, args *[]string, reply *GetEthTransactionsReply) error {
-	if len((*args)) == 0 {
-		return errors.New("missing token")
-	}
+var getAllTransactionsParams = params{"chain?", "request?"}
 
-	if err := l.authenticator.VerifyToken((*args)[0]); err != nil {
+// Returns a page of the cached transactions, see ListTransactionsRequest.
+func (l *Lime) GetAllTransactions(r *http.Request, args *ListTransactionsParams, reply *ListTransactionsReply) error {
+	request, err := args.request(getAllTransactionsParams)
+	if err != nil {
 		return err
 	}
 
-	transactions, err := l.txFetcher.FetchAllCachedTxByToken(r.Context(), (*args)[0])
+	return l.listTransactions(r.Context(), nil, &request, reply)
+}
+
+var getMyTransactionsParams = params{"token", "chain?", "request?"}
+
+// Returns a page of the transactions requested with the token, see ListTransactionsRequest.
+func (l *Lime) GetMyTransactions(r *http.Request, args *ListTransactionsParams, reply *ListTransactionsReply) error {
+	request, err := args.request(getMyTransactionsParams)
 	if err != nil {
 		return err
 	}
 
-	reply.Transactions = transactions
+	if request.Token == "" {
+		return errors.New("missing token")
+	}
 
-	return nil
+	if err := l.authenticator.VerifyToken(request.Token); err != nil {
+		return err
+	}
+
+	return l.listTransactions(r.Context(), &request.Token, &request, reply)
 }
 
 func (l *Lime) Authenticate(r *http.Request, request *AuthenticateRequest, reply *AuthenticateReply) error {
@@ -103,39 +129,1117 @@ func (l *Lime) Authenticate(r *http.Request, request *AuthenticateRequest, reply
 	return nil
 }
 
-func unhex(str string) []byte {
-	b, err := hex.DecodeString(strings.ReplaceAll(str, " ", ""))
+var resolveNameParams = params{"nameOrAddress", "chain?"}
+
+func (l *Lime) ResolveName(r *http.Request, args *[]string, reply *ResolveNameReply) error {
+	if err := resolveNameParams.check(*args); err != nil {
+		return err
+	}
+
+	backend, err := l.backend(resolveNameParams.value(*args, "chain"))
 	if err != nil {
-		panic(fmt.Sprintf("invalid hex string: %q", str))
+		return err
 	}
-	return b
+
+	if backend.NameResolver == nil {
+		return errors.New("ens resolution is disabled")
+	}
+
+	query := resolveNameParams.value(*args, "nameOrAddress")
+
+	if common.IsHexAddress(query) {
+		address := common.HexToAddress(query)
+		name, err := backend.NameResolver.LookupAddress(r.Context(), address)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			return fmt.Errorf("address (%s) has no verified ens name", address.Hex())
+		}
+
+		reply.Name = name
+		reply.Address = address.Hex()
+
+		return nil
+	}
+
+	address, err := backend.NameResolver.ResolveName(r.Context(), query)
+	if err != nil {
+		return err
+	}
+
+	reply.Name = query
+	reply.Address = address.Hex()
+
+	return nil
 }
 
-type AuthenticateRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+func (l *Lime) SimulateTransaction(r *http.Request, request *SimulateTransactionRequest, reply *SimulateTransactionReply) error {
+	backend, err := l.backend(request.Chain)
+	if err != nil {
+		return err
+	}
+
+	simulation, err := backend.Simulator.Simulate(r.Context(), request.CallRequest)
+	if err != nil {
+		return err
+	}
+
+	reply.Simulation = simulation
+
+	return nil
 }
 
-type AuthenticateReply struct {
-	Token string `json:"token"`
+// Pending transactions are followed until mined and their status changes are published to the user.
+func (l *Lime) FollowTransactions(r *http.Request, request *FollowTransactionsRequest, reply *GetEthTransactionsReply) error {
+	if len(request.Hashes) == 0 {
+		return errors.New("missing tx hashes")
+	}
+
+	username, err := l.authenticator.Username(request.Token)
+	if err != nil {
+		return err
+	}
+
+	backend, err := l.backend(request.Chain)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range request.Hashes {
+		if len(common.FromHex(hash)) != common.HashLength {
+			return fmt.Errorf("invalid tx hash (%s)", hash)
+		}
+	}
+
+	transactions, err := backend.TxFetcher.Follow(r.Context(), &model.TxOwner{Token: request.Token, Username: username}, request.Hashes)
+	if err != nil {
+		return err
+	}
+
+	reply.Transactions = backend.WithNames(r.Context(), transactions)
+
+	return nil
 }
 
-type GetEthTransactionsReply struct {
-	Transactions []model.Transaction `json:"transactions"`
+var getPendingByAddressParams = params{"address", "chain?"}
+
+// Served from the mempool index, oldest first.
+func (l *Lime) GetPendingByAddress(r *http.Request, args *[]string, reply *GetEthTransactionsReply) error {
+	if err := getPendingByAddressParams.check(*args); err != nil {
+		return err
+	}
+
+	address := getPendingByAddressParams.value(*args, "address")
+	if !common.IsHexAddress(address) {
+		return fmt.Errorf("invalid address (%s)", address)
+	}
+
+	chain := getPendingByAddressParams.value(*args, "chain")
+	chainID, err := l.chains.ChainID(chain)
+	if err != nil {
+		return err
+	}
+
+	backend, err := l.backend(chain)
+	if err != nil {
+		return err
+	}
+
+	if backend.Mempool == nil {
+		return fmt.Errorf("mempool is not observed on chain (%d)", chainID)
+	}
+
+	reply.Transactions = backend.WithNames(r.Context(), backend.Mempool.PendingByAddress(common.HexToAddress(address)))
+
+	return nil
 }
 
-type txFetcher interface {
-	FetchTx(ctx context.Context, token *string, txHashes []string) ([]model.Transaction, error)
-	FetchAllCachedTx(ctx context.Context) ([]model.Transaction, error)
-	FetchAllCachedTxByToken(ctx context.Context, token string) ([]model.Transaction, error)
+var getContractInfoParams = params{"address", "chain?"}
+
+// Proxies are resolved at the head, abiAddress holds the address whose ABI applies to the contract.
+func (l *Lime) GetContractInfo(r *http.Request, args *[]string, reply *GetContractInfoReply) error {
+	if err := getContractInfoParams.check(*args); err != nil {
+		return err
+	}
+
+	address := getContractInfoParams.value(*args, "address")
+	if !common.IsHexAddress(address) {
+		return fmt.Errorf("invalid address (%s)", address)
+	}
+
+	backend, err := l.backend(getContractInfoParams.value(*args, "chain"))
+	if err != nil {
+		return err
+	}
+
+	reply.ContractInfo, err = backend.ContractInspector.Inspect(r.Context(), common.HexToAddress(address))
+
+	return err
 }
 
-type authenticator interface {
-	Authenticate(ctx context.Context, username, password string) (string, error)
-	VerifyToken(token string) error
+var sendRawTransactionParams = params{"signedRawTx", "token", "chain?"}
+
+func (l *Lime) SendRawTransaction(r *http.Request, args *[]string, reply *SendRawTransactionReply) error {
+	if err := sendRawTransactionParams.check(*args); err != nil {
+		return err
+	}
+
+	token := sendRawTransactionParams.value(*args, "token")
+	username, err := l.authenticator.Username(token)
+	if err != nil {
+		return err
+	}
+
+	backend, err := l.backend(sendRawTransactionParams.value(*args, "chain"))
+	if err != nil {
+		return err
+	}
+
+	signedRawTx := sendRawTransactionParams.value(*args, "signedRawTx")
+	hash, err := backend.TxSender.SendRawTx(r.Context(), &model.TxOwner{Token: token, Username: username}, signedRawTx)
+	if err != nil {
+		return err
+	}
+
+	reply.TransactionHash = hash
+
+	return nil
+}
+
+var reserveNonceParams = params{"token", "address", "chain?"}
+
+// Only managed addresses have nonces reserved, the transaction signed with the nonce is sent
+// with SendRawTransaction.
+func (l *Lime) ReserveNonce(r *http.Request, args *[]string, reply *ReserveNonceReply) error {
+	nonceManager, username, address, err := l.nonceArgs(*args, reserveNonceParams)
+	if err != nil {
+		return err
+	}
+
+	reply.Nonce, err = nonceManager.Reserve(r.Context(), address, username)
+
+	return err
+}
+
+var releaseNonceParams = params{"token", "address", "nonce", "chain?"}
+
+// Releases a nonce the user reserved and will not send.
+func (l *Lime) ReleaseNonce(r *http.Request, args *[]string, reply *ReleaseNonceReply) error {
+	nonceManager, username, address, err := l.nonceArgs(*args, releaseNonceParams)
+	if err != nil {
+		return err
+	}
+
+	value := releaseNonceParams.value(*args, "nonce")
+	nonce, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid nonce (%s)", value)
+	}
+
+	reply.Released, err = nonceManager.Release(r.Context(), address, username, nonce)
+
+	return err
+}
+
+var getNonceGapsParams = params{"token", "address", "chain?"}
+
+// Gaps block the later transactions of the address until a transaction with the nonce, usually
+// a zero value transfer to itself, is sent.
+func (l *Lime) GetNonceGaps(r *http.Request, args *[]string, reply *GetNonceGapsReply) error {
+	nonceManager, _, address, err := l.nonceArgs(*args, getNonceGapsParams)
+	if err != nil {
+		return err
+	}
+
+	reply.Gaps, err = nonceManager.Gaps(r.Context(), address)
+
+	return err
+}
+
+var getFeeSuggestionsParams = params{"pendingTxHash?", "chain?"}
+
+func (l *Lime) GetFeeSuggestions(r *http.Request, args *[]string, reply *GetFeeSuggestionsReply) error {
+	if err := getFeeSuggestionsParams.check(*args); err != nil {
+		return err
+	}
+
+	backend, err := l.backend(getFeeSuggestionsParams.value(*args, "chain"))
+	if err != nil {
+		return err
+	}
+
+	var txHash *string
+	if hash := getFeeSuggestionsParams.value(*args, "pendingTxHash"); hash != "" {
+		txHash = &hash
+	}
+
+	suggestions, err := backend.GasOracle.FeeSuggestions(r.Context(), txHash)
+	if err != nil {
+		return err
+	}
+
+	reply.FeeSuggestions = suggestions
+
+	return nil
+}
+
+var getAccountStateParams = params{"address", "block?", "chain?"}
+
+// The block is a number or a tag and defaults to "latest".
+func (l *Lime) GetAccountState(r *http.Request, args *[]string, reply *GetAccountStateReply) error {
+	if err := getAccountStateParams.check(*args); err != nil {
+		return err
+	}
+
+	address := getAccountStateParams.value(*args, "address")
+	if !common.IsHexAddress(address) {
+		return fmt.Errorf("invalid address (%s)", address)
+	}
+
+	backend, err := l.backend(getAccountStateParams.value(*args, "chain"))
+	if err != nil {
+		return err
+	}
+
+	block := getAccountStateParams.value(*args, "block")
+	state, err := backend.AccountStateFetcher.FetchAccountState(r.Context(), common.HexToAddress(address), block)
+	if err != nil {
+		return err
+	}
+
+	reply.AccountState = state
+
+	return nil
+}
+
+func (l *Lime) GetProvenAccount(r *http.Request, request *GetProvenAccountRequest, reply *GetProvenAccountReply) error {
+	if !common.IsHexAddress(request.Address) {
+		return errors.New("missing or invalid address")
+	}
+
+	backend, err := l.backend(request.Chain)
+	if err != nil {
+		return err
+	}
+
+	account, err := backend.Prover.ProveAccount(r.Context(), common.HexToAddress(request.Address), request.Slots, request.Block)
+	if err != nil {
+		return err
+	}
+
+	reply.ProvenAccount = account
+
+	return nil
+}
+
+var watchAddressParams = params{"token", "address", "chain?"}
+
+func (l *Lime) WatchAddress(r *http.Request, args *[]string, reply *WatchlistReply) error {
+	username, chainID, address, err := l.watchArgs(*args, watchAddressParams)
+	if err != nil {
+		return err
+	}
+
+	if err := l.watchlists.WatchAddress(r.Context(), username, chainID, address); err != nil {
+		return err
+	}
+
+	reply.Addresses, err = l.watchlists.GetWatchedAddressesByUser(r.Context(), username, chainID)
+
+	return err
+}
+
+var unwatchAddressParams = params{"token", "address", "chain?"}
+
+func (l *Lime) UnwatchAddress(r *http.Request, args *[]string, reply *WatchlistReply) error {
+	username, chainID, address, err := l.watchArgs(*args, unwatchAddressParams)
+	if err != nil {
+		return err
+	}
+
+	if err := l.watchlists.UnwatchAddress(r.Context(), username, chainID, address); err != nil {
+		return err
+	}
+
+	reply.Addresses, err = l.watchlists.GetWatchedAddressesByUser(r.Context(), username, chainID)
+
+	return err
+}
+
+var getWatchedAddressesParams = params{"token", "chain?"}
+
+func (l *Lime) GetWatchedAddresses(r *http.Request, args *[]string, reply *WatchlistReply) error {
+	if err := getWatchedAddressesParams.check(*args); err != nil {
+		return err
+	}
+
+	username, err := l.authenticator.Username(getWatchedAddressesParams.value(*args, "token"))
+	if err != nil {
+		return err
+	}
+
+	chainID, err := l.chains.ChainID(getWatchedAddressesParams.value(*args, "chain"))
+	if err != nil {
+		return err
+	}
+
+	reply.Addresses, err = l.watchlists.GetWatchedAddressesByUser(r.Context(), username, chainID)
+
+	return err
+}
+
+var getNotificationsParams = params{"token", "includeAcknowledged?", "limit?"}
+
+// Notifications of all chains are returned, newest first.
+func (l *Lime) GetNotifications(r *http.Request, args *[]string, reply *GetNotificationsReply) error {
+	if err := getNotificationsParams.check(*args); err != nil {
+		return err
+	}
+
+	username, err := l.authenticator.Username(getNotificationsParams.value(*args, "token"))
+	if err != nil {
+		return err
+	}
+
+	includeAcknowledged := false
+	if value := getNotificationsParams.value(*args, "includeAcknowledged"); value != "" {
+		if includeAcknowledged, err = strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid includeAcknowledged (%s)", value)
+		}
+	}
+
+	limit := defaultNotificationsLimit
+	if value := getNotificationsParams.value(*args, "limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 || limit > maxNotificationsLimit {
+			return fmt.Errorf("invalid limit (%s), expected 1-%d", value, maxNotificationsLimit)
+		}
+	}
+
+	reply.Notifications, err = l.watchlists.GetNotifications(r.Context(), username, includeAcknowledged, limit)
+
+	return err
+}
+
+func (l *Lime) AcknowledgeNotifications(r *http.Request, request *AcknowledgeNotificationsRequest, reply *AcknowledgeNotificationsReply) error {
+	username, err := l.authenticator.Username(request.Token)
+	if err != nil {
+		return err
+	}
+
+	reply.Acknowledged, err = l.watchlists.AcknowledgeNotifications(r.Context(), username, request.IDs)
+
+	return err
+}
+
+// The secret signing the deliveries is only returned here.
+func (l *Lime) CreateWebhook(r *http.Request, request *CreateWebhookRequest, reply *CreateWebhookReply) error {
+	username, err := l.authenticator.Username(request.Token)
+	if err != nil {
+		return err
+	}
+
+	reply.Webhook, reply.Secret, err = l.webhooks.Subscribe(r.Context(), username, request.Url, request.EventTypes)
+
+	return err
+}
+
+func (l *Lime) DeleteWebhook(r *http.Request, request *DeleteWebhookRequest, reply *DeleteWebhookReply) error {
+	username, err := l.authenticator.Username(request.Token)
+	if err != nil {
+		return err
+	}
+
+	if err := l.webhooks.Unsubscribe(r.Context(), username, request.ID); err != nil {
+		return err
+	}
+
+	reply.Deleted = true
+
+	return nil
+}
+
+var getWebhooksParams = params{"token"}
+
+func (l *Lime) GetWebhooks(r *http.Request, args *[]string, reply *GetWebhooksReply) error {
+	if err := getWebhooksParams.check(*args); err != nil {
+		return err
+	}
+
+	username, err := l.authenticator.Username(getWebhooksParams.value(*args, "token"))
+	if err != nil {
+		return err
+	}
+
+	reply.Webhooks, err = l.webhooks.Subscriptions(r.Context(), username)
+
+	return err
+}
+
+// Deliveries are returned newest first, each with the log of its attempts.
+func (l *Lime) GetWebhookDeliveries(r *http.Request, request *GetWebhookDeliveriesRequest, reply *GetWebhookDeliveriesReply) error {
+	username, err := l.authenticator.Username(request.Token)
+	if err != nil {
+		return err
+	}
+
+	limit := request.Limit
+	if limit == 0 {
+		limit = defaultDeliveriesLimit
+	}
+	if limit < 0 || limit > maxDeliveriesLimit {
+		return fmt.Errorf("invalid limit (%d), expected 1-%d", limit, maxDeliveriesLimit)
+	}
+
+	reply.Deliveries, err = l.webhooks.Deliveries(r.Context(), username, request.WebhookID, limit)
+
+	return err
+}
+
+// PositionalParams names the params of the methods that take a list of strings, optional ones end with "?".
+// The names come from the declarations the methods validate their params with.
+func (l *Lime) PositionalParams(method string) []string {
+	return positionalParams[method]
+}
+
+func (l *Lime) listTransactions(ctx context.Context, token *string, request *ListTransactionsRequest, reply *ListTransactionsReply) error {
+	backend, err := l.backend(request.Chain)
+	if err != nil {
+		return err
+	}
+
+	*reply, err = backend.ListTransactions(ctx, token, *request)
+
+	return err
+}
+
+func (l *Lime) watchArgs(args []string, declared params) (string, uint64, string, error) {
+	if err := declared.check(args); err != nil {
+		return "", 0, "", err
+	}
+
+	username, err := l.authenticator.Username(declared.value(args, "token"))
+	if err != nil {
+		return "", 0, "", err
+	}
+
+	address := declared.value(args, "address")
+	if !common.IsHexAddress(address) {
+		return "", 0, "", fmt.Errorf("invalid address (%s)", address)
+	}
+
+	chainID, err := l.chains.ChainID(declared.value(args, "chain"))
+	if err != nil {
+		return "", 0, "", err
+	}
+
+	return username, chainID, common.HexToAddress(address).Hex(), nil
+}
+
+func (l *Lime) nonceArgs(args []string, declared params) (nonceManager, string, common.Address, error) {
+	if err := declared.check(args); err != nil {
+		return nil, "", common.Address{}, err
+	}
+
+	username, err := l.authenticator.Username(declared.value(args, "token"))
+	if err != nil {
+		return nil, "", common.Address{}, err
+	}
+
+	value := declared.value(args, "address")
+	if !common.IsHexAddress(value) {
+		return nil, "", common.Address{}, fmt.Errorf("invalid address (%s)", value)
+	}
+
+	backend, err := l.backend(declared.value(args, "chain"))
+	if err != nil {
+		return nil, "", common.Address{}, err
+	}
+
+	address := common.HexToAddress(value)
+	if backend.NonceManager == nil || !backend.NonceManager.Manages(address) {
+		return nil, "", common.Address{}, fmt.Errorf("address (%s) is not managed", address.Hex())
+	}
+
+	if !backend.NonceManager.Allows(username) {
+		return nil, "", common.Address{}, fmt.Errorf("user (%s) may not manage nonces", username)
+	}
+
+	return backend.NonceManager, username, address, nil
+}
+
+func (l *Lime) backend(chain string) (ChainBackend, error) {
+	chainID, err := l.chains.ChainID(chain)
+	if err != nil {
+		return ChainBackend{}, err
+	}
+
+	backend, ok := l.backends[chainID]
+	if !ok {
+		return ChainBackend{}, fmt.Errorf("chain (%d) is not served", chainID)
+	}
+
+	return backend, nil
+}
+
+// ListTransactions returns a page of the cached transactions of the chain, only the ones requested
+// with the token when it is set. Invalid filters are returned as an InvalidRequestError.
+func (b ChainBackend) ListTransactions(ctx context.Context, token *string, request ListTransactionsRequest) (ListTransactionsReply, error) {
+	query, err := transactionsQuery(token, request)
+	if err != nil {
+		return ListTransactionsReply{}, &InvalidRequestError{Err: err}
+	}
+
+	// One more transaction tells whether there is a next page
+	limit := query.Limit
+	query.Limit++
+
+	transactions, err := b.TxFetcher.ListCachedTxs(ctx, query)
+	if err != nil {
+		return ListTransactionsReply{}, err
+	}
+
+	reply := ListTransactionsReply{}
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+		cursor := model.PositionOf(transactions[limit-1]).Cursor()
+		reply.NextCursor = &cursor
+	}
+
+	reply.Transactions = b.WithNames(ctx, transactions)
+
+	return reply, nil
+}
+
+func transactionsQuery(token *string, request ListTransactionsRequest) (model.TxQuery, error) {
+	query := model.TxQuery{
+		Filter: model.TxFilter{
+			FromBlock: request.FromBlock,
+			ToBlock:   request.ToBlock,
+			HasLogs:   request.HasLogs,
+		},
+		Token: token,
+		Limit: defaultTransactionsLimit,
+	}
+
+	if request.Limit > 0 {
+		query.Limit = request.Limit
+	}
+	if query.Limit > maxTransactionsLimit {
+		query.Limit = maxTransactionsLimit
+	}
+
+	switch request.Order {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return model.TxQuery{}, fmt.Errorf("invalid order (%s)", request.Order)
+	}
+
+	if request.Status != "" {
+		status, err := model.ParseTxStatus(request.Status)
+		if err != nil {
+			return model.TxQuery{}, err
+		}
+		query.Filter.Status = &status
+	}
+
+	var err error
+	if query.Filter.From, err = optionalAddress(request.From); err != nil {
+		return model.TxQuery{}, err
+	}
+	if query.Filter.To, err = optionalAddress(request.To); err != nil {
+		return model.TxQuery{}, err
+	}
+	if query.Filter.ContractAddress, err = optionalAddress(request.ContractAddress); err != nil {
+		return model.TxQuery{}, err
+	}
+
+	if request.Cursor != "" {
+		position, err := model.ParseTxCursor(request.Cursor)
+		if err != nil {
+			return model.TxQuery{}, err
+		}
+		query.After = &position
+	}
+
+	return query, nil
+}
+
+// WithNames fills in the names of the addresses when names are resolved. Every address is looked
+// up once, with at most maxConcurrentLookups lookups at a time.
+func (b ChainBackend) WithNames(ctx context.Context, transactions []model.Transaction) []model.Transaction {
+	if b.NameResolver == nil {
+		return transactions
+	}
+
+	addresses := []string{}
+	names := make(map[string]*string)
+	for _, tx := range transactions {
+		for _, address := range []*string{&tx.From, tx.To, tx.ContractAddress} {
+			if address == nil || !common.IsHexAddress(*address) {
+				continue
+			}
+			key := common.HexToAddress(*address).Hex()
+			if _, ok := names[key]; !ok {
+				names[key] = nil
+				addresses = append(addresses, key)
+			}
+		}
+	}
+
+	resolved := make([]*string, len(addresses))
+	semaphore := make(chan struct{}, maxConcurrentLookups)
+
+	var wg sync.WaitGroup
+	wg.Add(len(addresses))
+
+	for i, address := range addresses {
+		go func(i int, address string) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			resolved[i] = b.LookupName(ctx, &address)
+		}(i, address)
+	}
+
+	wg.Wait()
+
+	for i, address := range addresses {
+		names[address] = resolved[i]
+	}
+
+	nameOf := func(address *string) *string {
+		if address == nil || !common.IsHexAddress(*address) {
+			return nil
+		}
+		return names[common.HexToAddress(*address).Hex()]
+	}
+
+	for i := range transactions {
+		tx := &transactions[i]
+		tx.FromName = nameOf(&tx.From)
+		tx.ToName = nameOf(tx.To)
+		tx.ContractAddressName = nameOf(tx.ContractAddress)
+	}
+
+	return transactions
+}
+
+// LookupName returns nil when the address has no name or names are not resolved.
+func (b ChainBackend) LookupName(ctx context.Context, address *string) *string {
+	if b.NameResolver == nil || address == nil || !common.IsHexAddress(*address) {
+		return nil
+	}
+
+	name, err := b.NameResolver.LookupAddress(ctx, common.HexToAddress(*address))
+	if err != nil {
+		log.Println(fmt.Errorf("failed to lookup ens name of (%s): %s", *address, err))
+		return nil
+	}
+
+	if name == "" {
+		return nil
+	}
+
+	return &name
+}
+
+// check fails when a required param is missing or empty, or when there are more params than declared.
+func (p params) check(args []string) error {
+	if len(args) > len(p) {
+		return fmt.Errorf("expected at most %d params", len(p))
+	}
+
+	missing := []string{}
+	for i, name := range p {
+		if !strings.HasSuffix(name, "?") && (i >= len(args) || args[i] == "") {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// value returns the param by its declared name, without the "?" of optional params. Params that
+// were not sent are empty.
+func (p params) value(args []string, name string) string {
+	for i, declared := range p {
+		if strings.TrimSuffix(declared, "?") != name {
+			continue
+		}
+		if i < len(args) {
+			return args[i]
+		}
+		return ""
+	}
+
+	panic(fmt.Sprintf("param (%s) is not declared", name))
+}
+
+func optionalAddress(value string) (*string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if !common.IsHexAddress(value) {
+		return nil, fmt.Errorf("invalid address (%s)", value)
+	}
+
+	address := common.HexToAddress(value).Hex()
+	return &address, nil
+}
+
+func unhex(str string) []byte {
+	b, err := hex.DecodeString(strings.ReplaceAll(str, " ", ""))
+	if err != nil {
+		panic(fmt.Sprintf("invalid hex string: %q", str))
+	}
+	return b
+}
+
+// params names the positional params of a method in order, optional ones end with "?".
+type params []string
+
+var positionalParams = map[string]params{
+	"GetAllTransactions":  getAllTransactionsParams,
+	"GetMyTransactions":   getMyTransactionsParams,
+	"GetEthTransactions":  getEthTransactionsParams,
+	"ResolveName":         resolveNameParams,
+	"GetPendingByAddress": getPendingByAddressParams,
+	"GetContractInfo":     getContractInfoParams,
+	"SendRawTransaction":  sendRawTransactionParams,
+	"ReserveNonce":        reserveNonceParams,
+	"ReleaseNonce":        releaseNonceParams,
+	"GetNonceGaps":        getNonceGapsParams,
+	"GetFeeSuggestions":   getFeeSuggestionsParams,
+	"GetAccountState":     getAccountStateParams,
+	"WatchAddress":        watchAddressParams,
+	"UnwatchAddress":      unwatchAddressParams,
+	"GetWatchedAddresses": getWatchedAddressesParams,
+	"GetNotifications":    getNotificationsParams,
+	"GetWebhooks":         getWebhooksParams,
+}
+
+const (
+	maxConcurrentLookups      = 8
+	defaultTransactionsLimit  = 100
+	maxTransactionsLimit      = 1000
+	defaultNotificationsLimit = 100
+	maxNotificationsLimit     = 1000
+	defaultDeliveriesLimit    = 50
+	maxDeliveriesLimit        = 500
+)
+
+// ListTransactionsRequest filters by status (name or number), block range and addresses. Transactions
+// are ordered by block number and index, "asc" (default) or "desc", and pending ones sort after mined
+// ones. The next page starts after the nextCursor of the previous reply.
+type ListTransactionsRequest struct {
+	Token           string  `json:"token"`
+	Chain           string  `json:"chain"`
+	Status          string  `json:"status"`
+	FromBlock       *uint64 `json:"fromBlock"`
+	ToBlock         *uint64 `json:"toBlock"`
+	From            string  `json:"from"`
+	To              string  `json:"to"`
+	ContractAddress string  `json:"contractAddress"`
+	HasLogs         *bool   `json:"hasLogs"`
+	Order           string  `json:"order"`
+	Limit           int     `json:"limit"`
+	Cursor          string  `json:"cursor"`
+}
+
+// ListTransactionsParams are the positional params of the listings, optionally followed by
+// a ListTransactionsRequest object with the filters. The object may also be sent alone.
+type ListTransactionsParams []json.RawMessage
+
+// request reads the positional params declared before the trailing request object.
+func (p ListTransactionsParams) request(declared params) (ListTransactionsRequest, error) {
+	names := []string{}
+	for _, name := range declared[:len(declared)-1] {
+		names = append(names, strings.TrimSuffix(name, "?"))
+	}
+
+	request := ListTransactionsRequest{}
+
+	positional := []string{}
+	for i, param := range p {
+		if trimmed := bytes.TrimSpace(param); len(trimmed) > 0 && trimmed[0] == '{' {
+			if i != len(p)-1 {
+				return ListTransactionsRequest{}, errors.New("the request object must be the last param")
+			}
+			if err := json.Unmarshal(param, &request); err != nil {
+				return ListTransactionsRequest{}, fmt.Errorf("invalid request object: %s", err)
+			}
+			break
+		}
+
+		var value string
+		if err := json.Unmarshal(param, &value); err != nil {
+			return ListTransactionsRequest{}, fmt.Errorf("param %d must be a string", i)
+		}
+		positional = append(positional, value)
+	}
+
+	if len(positional) > len(names) {
+		return ListTransactionsRequest{}, fmt.Errorf("expected at most %d positional params", len(names))
+	}
+
+	for i, value := range positional {
+		if value == "" {
+			continue
+		}
+		switch names[i] {
+		case "token":
+			request.Token = value
+		case "chain":
+			request.Chain = value
+		}
+	}
+
+	return request, nil
+}
+
+type ListTransactionsReply struct {
+	Transactions []model.Transaction `json:"transactions"`
+	NextCursor   *string             `json:"nextCursor"`
+}
+
+// InvalidRequestError is a request rejected before the node or the database are queried.
+type InvalidRequestError struct {
+	Err error
+}
+
+func (e *InvalidRequestError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidRequestError) Unwrap() error {
+	return e.Err
+}
+
+type AuthenticateRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type AuthenticateReply struct {
+	Token string `json:"token"`
+}
+
+type ResolveNameReply struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+type SimulateTransactionRequest struct {
+	Chain string `json:"chain"`
+	model.CallRequest
+}
+
+type SimulateTransactionReply struct {
+	model.Simulation
+}
+
+type FollowTransactionsRequest struct {
+	Token  string   `json:"token"`
+	Hashes []string `json:"hashes"`
+	Chain  string   `json:"chain"`
+}
+
+type SendRawTransactionReply struct {
+	TransactionHash string `json:"transactionHash"`
+}
+
+type ReserveNonceReply struct {
+	Nonce uint64 `json:"nonce"`
+}
+
+type ReleaseNonceReply struct {
+	Released bool `json:"released"`
+}
+
+type GetNonceGapsReply struct {
+	Gaps []uint64 `json:"gaps"`
+}
+
+type GetFeeSuggestionsReply struct {
+	model.FeeSuggestions
+}
+
+type GetAccountStateReply struct {
+	model.AccountState
+}
+
+type GetProvenAccountRequest struct {
+	Address string   `json:"address"`
+	Slots   []string `json:"slots"`
+	Block   string   `json:"block"`
+	Chain   string   `json:"chain"`
+}
+
+type GetProvenAccountReply struct {
+	model.ProvenAccount
+}
+
+type GetContractInfoReply struct {
+	model.ContractInfo
+}
+
+type WatchlistReply struct {
+	Addresses []model.WatchedAddress `json:"addresses"`
+}
+
+type GetNotificationsReply struct {
+	Notifications []model.Notification `json:"notifications"`
+}
+
+type AcknowledgeNotificationsRequest struct {
+	Token string  `json:"token"`
+	IDs   []int64 `json:"ids"`
+}
+
+type AcknowledgeNotificationsReply struct {
+	Acknowledged int64 `json:"acknowledged"`
+}
+
+type CreateWebhookRequest struct {
+	Token      string            `json:"token"`
+	Url        string            `json:"url"`
+	EventTypes []model.EventType `json:"eventTypes"`
+}
+
+type CreateWebhookReply struct {
+	Webhook model.WebhookSubscription `json:"webhook"`
+	Secret  string                    `json:"secret"`
+}
+
+type DeleteWebhookRequest struct {
+	Token string `json:"token"`
+	ID    int64  `json:"id"`
+}
+
+type DeleteWebhookReply struct {
+	Deleted bool `json:"deleted"`
+}
+
+type GetWebhooksReply struct {
+	Webhooks []model.WebhookSubscription `json:"webhooks"`
+}
+
+type GetWebhookDeliveriesRequest struct {
+	Token     string `json:"token"`
+	WebhookID int64  `json:"webhookId"`
+	Limit     int    `json:"limit"`
+}
+
+type GetWebhookDeliveriesReply struct {
+	Deliveries []model.WebhookDelivery `json:"deliveries"`
+}
+
+type GetEthTransactionsReply struct {
+	Transactions []model.Transaction `json:"transactions"`
+}
+
+type txFetcher interface {
+	FetchTx(ctx context.Context, owner *model.TxOwner, txHashes []string) ([]model.Transaction, error)
+	ListCachedTxs(ctx context.Context, query model.TxQuery) ([]model.Transaction, error)
+	Follow(ctx context.Context, owner *model.TxOwner, txHashes []string) ([]model.Transaction, error)
+}
+
+type authenticator interface {
+	Authenticate(ctx context.Context, username, password string) (string, error)
+	VerifyToken(token string) error
+	Username(token string) (string, error)
+}
+
+type watchlists interface {
+	WatchAddress(ctx context.Context, username string, chainID uint64, address string) error
+	UnwatchAddress(ctx context.Context, username string, chainID uint64, address string) error
+	GetWatchedAddressesByUser(ctx context.Context, username string, chainID uint64) ([]model.WatchedAddress, error)
+	GetNotifications(ctx context.Context, username string, includeAcknowledged bool, limit int) ([]model.Notification, error)
+	AcknowledgeNotifications(ctx context.Context, username string, ids []int64) (int64, error)
+}
+
+type webhooks interface {
+	Subscribe(ctx context.Context, username, url string, eventTypes []model.EventType) (model.WebhookSubscription, string, error)
+	Unsubscribe(ctx context.Context, username string, id int64) error
+	Subscriptions(ctx context.Context, username string) ([]model.WebhookSubscription, error)
+	Deliveries(ctx context.Context, username string, subscriptionID int64, limit int) ([]model.WebhookDelivery, error)
+}
+
+type nameResolver interface {
+	LookupAddress(ctx context.Context, address common.Address) (string, error)
+	ResolveName(ctx context.Context, name string) (common.Address, error)
+}
+
+type simulator interface {
+	Simulate(ctx context.Context, request model.CallRequest) (model.Simulation, error)
+}
+
+type txSender interface {
+	SendRawTx(ctx context.Context, owner *model.TxOwner, rawTx string) (string, error)
+}
+
+type contractInspector interface {
+	Inspect(ctx context.Context, address common.Address) (model.ContractInfo, error)
+}
+
+type mempool interface {
+	PendingByAddress(address common.Address) []model.Transaction
+}
+
+type gasOracle interface {
+	FeeSuggestions(ctx context.Context, txHash *string) (model.FeeSuggestions, error)
+}
+
+type nonceManager interface {
+	Manages(address common.Address) bool
+	Allows(username string) bool
+	Reserve(ctx context.Context, address common.Address, username string) (uint64, error)
+	Release(ctx context.Context, address common.Address, username string, nonce uint64) (bool, error)
+	Gaps(ctx context.Context, address common.Address) ([]uint64, error)
+}
+
+type accountStateFetcher interface {
+	FetchAccountState(ctx context.Context, address common.Address, block string) (model.AccountState, error)
+}
+
+type prover interface {
+	ProveAccount(ctx context.Context, address common.Address, slots []string, block string) (model.ProvenAccount, error)
+}
+
+type chains interface {
+	ChainID(selector string) (uint64, error)
+}
+
+// ChainBackend groups the per-chain dependencies of the service. Optional
+// features are left nil when disabled.
+type ChainBackend struct {
+	TxFetcher    txFetcher
+	NameResolver nameResolver
+	Simulator    simulator
+	TxSender     txSender
+	GasOracle    gasOracle
+
+	AccountStateFetcher accountStateFetcher
+	Prover              prover
+	ContractInspector   contractInspector
+	Mempool             mempool
+	NonceManager        nonceManager
 }
 
 type Lime struct {
-	txFetcher     txFetcher
+	chains        chains
+	backends      map[uint64]ChainBackend
 	authenticator authenticator
+	watchlists    watchlists
+	webhooks      webhooks
 }
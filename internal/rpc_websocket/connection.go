@@ -0,0 +1,407 @@
+package rpcwebsocket
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	rpccodecs "github.com/avalkov/eth-node-interaction/internal/rpc_codecs"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gorilla/websocket"
+)
+
+func newConnection(handler *handler, conn *websocket.Conn, token, username string) *connection {
+	return &connection{
+		handler:       handler,
+		conn:          conn,
+		token:         token,
+		username:      username,
+		subscriptions: make(map[string]subscription),
+	}
+}
+
+// serve answers the requests of the connection one by one until it is closed.
+// Subscription notifications are written concurrently by push.
+func (c *connection) serve(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer c.conn.Close()
+
+	events, unsubscribe := c.handler.events.Subscribe()
+	defer unsubscribe()
+
+	go c.push(ctx, events)
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, message, err := c.conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Println(fmt.Errorf("websocket of (%s): %s", c.username, err))
+			}
+			return
+		}
+
+		if response := c.handle(ctx, message); len(response) > 0 {
+			if err := c.write(websocket.TextMessage, response); err != nil {
+				log.Println(fmt.Errorf("websocket of (%s): %s", c.username, err))
+				return
+			}
+		}
+	}
+}
+
+// handle returns nothing for requests without an id, like the HTTP transport does. Batches and
+// requests that cannot be decoded are forwarded, so they are answered as over HTTP. Subscriptions
+// are answered in the protocol version of the request and cannot be part of batches.
+func (c *connection) handle(ctx context.Context, message []byte) []byte {
+	if bytes.HasPrefix(bytes.TrimSpace(message), []byte("[")) {
+		return c.forward(ctx, message, rpcRequest{})
+	}
+
+	var request rpcRequest
+	if err := json.Unmarshal(message, &request); err != nil || !knownVersions[request.Version] {
+		return c.forward(ctx, message, rpcRequest{})
+	}
+
+	var result interface{}
+	var err error
+
+	switch request.Method {
+	case "lime_subscribe":
+		result, err = c.subscribe(ctx, request.Version, request.Params)
+	case "lime_unsubscribe":
+		result, err = c.unsubscribe(request.Params)
+	default:
+		return c.forward(ctx, message, request)
+	}
+
+	return rpccodecs.EncodeResponse(request.Version, request.Id, result, err)
+}
+
+// forward hands the request to the JSON-RPC handler of the HTTP transport, so every lime
+// method behaves the same on both transports.
+func (c *connection) forward(ctx context.Context, message []byte, original rpcRequest) []byte {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewReader(message))
+	if err != nil {
+		return rpccodecs.EncodeResponse(original.Version, original.Id, nil, err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response := &responseBuffer{header: make(http.Header), status: http.StatusOK}
+	c.handler.rpcHandler.ServeHTTP(response, request)
+
+	// The rpc server answers malformed requests of the original protocol with plain text
+	if response.status != http.StatusOK {
+		return rpccodecs.EncodeResponse(original.Version, original.Id, nil, errors.New(strings.TrimSpace(response.body.String())))
+	}
+
+	return response.body.Bytes()
+}
+
+func (c *connection) subscribe(ctx context.Context, version string, params json.RawMessage) (SubscribeReply, error) {
+	var requests []SubscribeRequest
+	if err := json.Unmarshal(params, &requests); err != nil || len(requests) != 1 {
+		return SubscribeReply{}, errors.New("expected a single subscription request")
+	}
+	request := requests[0]
+
+	sub := subscription{kind: request.Kind, version: version}
+	reply := SubscribeReply{}
+
+	switch request.Kind {
+	case TransactionsSubscription:
+		if len(request.Hashes) == 0 {
+			return SubscribeReply{}, errors.New("missing tx hashes")
+		}
+
+		var followed struct {
+			Transactions []model.Transaction `json:"transactions"`
+		}
+		if err := c.call(ctx, "lime_followTransactions", map[string]interface{}{
+			"token":  c.token,
+			"hashes": request.Hashes,
+			"chain":  request.Chain,
+		}, &followed); err != nil {
+			return SubscribeReply{}, err
+		}
+
+		sub.hashes = make(map[common.Hash]struct{})
+		for _, hash := range request.Hashes {
+			sub.hashes[common.HexToHash(hash)] = struct{}{}
+		}
+		reply.Transactions = followed.Transactions
+
+	case NewHeadsSubscription:
+
+	case WatchMatchesSubscription:
+		// Without a chain the matches of every chain are pushed
+		if request.Chain == "" {
+			sub.allChains = true
+		}
+
+	default:
+		return SubscribeReply{}, fmt.Errorf("unknown subscription kind (%s)", request.Kind)
+	}
+
+	if !sub.allChains {
+		chainID, err := c.handler.chains.ChainID(request.Chain)
+		if err != nil {
+			return SubscribeReply{}, err
+		}
+		sub.chainID = chainID
+	}
+
+	id, err := newSubscriptionID()
+	if err != nil {
+		return SubscribeReply{}, err
+	}
+
+	c.subscriptionsMu.Lock()
+	defer c.subscriptionsMu.Unlock()
+
+	if len(c.subscriptions) >= maxSubscriptions {
+		return SubscribeReply{}, fmt.Errorf("too many subscriptions, at most %d per connection", maxSubscriptions)
+	}
+	c.subscriptions[id] = sub
+
+	reply.Subscription = id
+
+	return reply, nil
+}
+
+func (c *connection) unsubscribe(params json.RawMessage) (bool, error) {
+	var ids []string
+	if err := json.Unmarshal(params, &ids); err != nil || len(ids) != 1 {
+		return false, errors.New("expected a single subscription id")
+	}
+
+	c.subscriptionsMu.Lock()
+	defer c.subscriptionsMu.Unlock()
+
+	if _, ok := c.subscriptions[ids[0]]; !ok {
+		return false, nil
+	}
+	delete(c.subscriptions, ids[0])
+
+	return true, nil
+}
+
+// push writes the events matching the subscriptions, keeps the connection alive and closes it
+// once the token expires.
+func (c *connection) push(ctx context.Context, events <-chan model.Event) {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.handler.authenticator.Username(c.token); err != nil {
+				c.write(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "token expired"))
+				c.conn.Close()
+				return
+			}
+			if err := c.write(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			for _, match := range c.matching(event) {
+				notification, err := json.Marshal(rpcNotification{
+					Version: match.version,
+					Method:  "lime_subscription",
+					Params:  subscriptionResult{Subscription: match.id, Result: event},
+				})
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+				if err := c.write(websocket.TextMessage, notification); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *connection) matching(event model.Event) []subscriptionMatch {
+	c.subscriptionsMu.Lock()
+	defer c.subscriptionsMu.Unlock()
+
+	matches := []subscriptionMatch{}
+	for id, sub := range c.subscriptions {
+		if sub.matches(c.username, event) {
+			matches = append(matches, subscriptionMatch{id: id, version: sub.version})
+		}
+	}
+	return matches
+}
+
+// call invokes a lime method through the JSON-RPC handler on behalf of the connection.
+func (c *connection) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	message, err := json.Marshal(map[string]interface{}{
+		"method": method,
+		"params": []interface{}{params},
+		"id":     0,
+	})
+	if err != nil {
+		return err
+	}
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  *string         `json:"error"`
+	}
+	if err := json.Unmarshal(c.forward(ctx, message, rpcRequest{}), &response); err != nil {
+		return err
+	}
+
+	if response.Error != nil {
+		return errors.New(*response.Error)
+	}
+
+	return json.Unmarshal(response.Result, result)
+}
+
+func (c *connection) write(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.conn.WriteMessage(messageType, data)
+}
+
+func (s subscription) matches(username string, event model.Event) bool {
+	if !s.allChains && event.ChainID != s.chainID {
+		return false
+	}
+
+	switch s.kind {
+	case TransactionsSubscription:
+		if event.Username != username || event.Transaction == nil {
+			return false
+		}
+		_, ok := s.hashes[common.HexToHash(event.Transaction.TransactionHash)]
+		return ok
+	case NewHeadsSubscription:
+		return event.Type == model.NewBlockEvent
+	case WatchMatchesSubscription:
+		return event.Type == model.WatchMatchEvent && event.Username == username
+	}
+
+	return false
+}
+
+func newSubscriptionID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hexutil.Encode(id), nil
+}
+
+func (rb *responseBuffer) Header() http.Header {
+	return rb.header
+}
+
+func (rb *responseBuffer) Write(data []byte) (int, error) {
+	return rb.body.Write(data)
+}
+
+func (rb *responseBuffer) WriteHeader(status int) {
+	rb.status = status
+}
+
+type SubscriptionKind string
+
+const (
+	TransactionsSubscription SubscriptionKind = "transactions"
+	NewHeadsSubscription     SubscriptionKind = "newHeads"
+	WatchMatchesSubscription SubscriptionKind = "watchMatches"
+)
+
+const maxSubscriptions = 32
+
+// Requests of other versions are rejected by the rpc handler
+var knownVersions = map[string]bool{"": true, "1.0": true, "2.0": true}
+
+// SubscribeRequest selects what is pushed. Hashes are only used by transaction subscriptions.
+type SubscribeRequest struct {
+	Kind   SubscriptionKind `json:"kind"`
+	Hashes []string         `json:"hashes"`
+	Chain  string           `json:"chain"`
+}
+
+// SubscribeReply carries the current state of subscribed transactions, later changes are pushed.
+type SubscribeReply struct {
+	Subscription string              `json:"subscription"`
+	Transactions []model.Transaction `json:"transactions,omitempty"`
+}
+
+type rpcRequest struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	Id      json.RawMessage `json:"id"`
+}
+
+// Notifications of subscriptions made with JSON-RPC 2.0 carry the version.
+type rpcNotification struct {
+	Version string             `json:"jsonrpc,omitempty"`
+	Method  string             `json:"method"`
+	Params  subscriptionResult `json:"params"`
+}
+
+type subscriptionResult struct {
+	Subscription string      `json:"subscription"`
+	Result       model.Event `json:"result"`
+}
+
+type subscriptionMatch struct {
+	id      string
+	version string
+}
+
+type subscription struct {
+	kind      SubscriptionKind
+	version   string
+	chainID   uint64
+	allChains bool
+	hashes    map[common.Hash]struct{}
+}
+
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+type connection struct {
+	handler  *handler
+	conn     *websocket.Conn
+	token    string
+	username string
+	writeMu  sync.Mutex
+
+	subscriptionsMu sync.Mutex
+	subscriptions   map[string]subscription
+}
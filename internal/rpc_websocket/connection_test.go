@@ -0,0 +1,124 @@
+package rpcwebsocket
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	rpccodecs "github.com/avalkov/eth-node-interaction/internal/rpc_codecs"
+	"github.com/gorilla/rpc"
+)
+
+func TestHandleAnswersInTheVersionOfTheRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			"2.0 unsubscribe",
+			`{"jsonrpc":"2.0","method":"lime_unsubscribe","params":["0x01"],"id":1}`,
+			`{"jsonrpc":"2.0","result":false,"id":1}`,
+		},
+		{
+			"2.0 unsubscribe with invalid params",
+			`{"jsonrpc":"2.0","method":"lime_unsubscribe","params":[],"id":1}`,
+			`{"jsonrpc":"2.0","error":{"code":-32000,"message":"expected a single subscription id"},"id":1}`,
+		},
+		{
+			"2.0 subscribe with an unknown kind",
+			`{"jsonrpc":"2.0","method":"lime_subscribe","params":[{"kind":"blocks"}],"id":"s"}`,
+			`{"jsonrpc":"2.0","error":{"code":-32000,"message":"unknown subscription kind (blocks)"},"id":"s"}`,
+		},
+		{
+			"original protocol unsubscribe",
+			`{"method":"lime_unsubscribe","params":["0x01"],"id":1}`,
+			`{"result":false,"error":null,"id":1}`,
+		},
+		{
+			"original protocol error",
+			`{"method":"lime_unsubscribe","params":[],"id":1}`,
+			`{"result":null,"error":"expected a single subscription id","id":1}`,
+		},
+		{
+			"2.0 notification",
+			`{"jsonrpc":"2.0","method":"lime_unsubscribe","params":["0x01"]}`,
+			``,
+		},
+		{
+			"malformed request",
+			`{"jsonrpc":"2.0","method":`,
+			`{"jsonrpc":"2.0","error":{"code":-32700,"message":"parse error: unexpected end of JSON input"},"id":null}`,
+		},
+		{
+			"invalid request",
+			`{"jsonrpc":"2.0","params":["0x01"],"id":1}`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request: missing method"},"id":1}`,
+		},
+		{
+			"unsupported version",
+			`{"jsonrpc":"3.0","method":"lime_unsubscribe","params":["0x01"],"id":1}`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"unsupported jsonrpc version (3.0)"},"id":1}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newTestConnection(t)
+			if got := trim(c.handle(context.Background(), []byte(test.message))); got != test.want {
+				t.Fatalf("response = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNotificationsCarryTheVersionOfTheSubscription(t *testing.T) {
+	c := newTestConnection(t)
+
+	var reply struct {
+		Result SubscribeReply `json:"result"`
+	}
+	response := c.handle(context.Background(), []byte(`{"jsonrpc":"2.0","method":"lime_subscribe","params":[{"kind":"newHeads"}],"id":1}`))
+	if err := json.Unmarshal(response, &reply); err != nil || reply.Result.Subscription == "" {
+		t.Fatalf("subscribe response = %s", response)
+	}
+
+	c.handle(context.Background(), []byte(`{"method":"lime_subscribe","params":[{"kind":"newHeads"}],"id":2}`))
+
+	matches := c.matching(model.Event{Type: model.NewBlockEvent, ChainID: 1})
+	if len(matches) != 2 {
+		t.Fatalf("%d matching subscriptions, want 2", len(matches))
+	}
+	for _, match := range matches {
+		want := ""
+		if match.id == reply.Result.Subscription {
+			want = "2.0"
+		}
+		if match.version != want {
+			t.Fatalf("subscription (%s) has version %q, want %q", match.id, match.version, want)
+		}
+	}
+}
+
+func newTestConnection(t *testing.T) *connection {
+	t.Helper()
+
+	server := rpc.NewServer()
+	server.RegisterCodec(rpccodecs.NewCustomRequestsCodec(), "application/json")
+
+	h := &handler{rpcHandler: rpccodecs.NewHandler(server, 10, 1), chains: testChains{}}
+	return newConnection(h, nil, "token", "user")
+}
+
+// Forwarded responses end with the newline of the encoder
+func trim(response []byte) string {
+	return strings.TrimSpace(string(response))
+}
+
+type testChains struct{}
+
+func (testChains) ChainID(selector string) (uint64, error) {
+	return 1, nil
+}
@@ -0,0 +1,75 @@
+package rpcwebsocket
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/gorilla/websocket"
+)
+
+func NewHandler(rpcHandler http.Handler, authenticator authenticator, chains chains, events events) *handler {
+	return &handler{
+		rpcHandler:    rpcHandler,
+		authenticator: authenticator,
+		chains:        chains,
+		events:        events,
+		upgrader: websocket.Upgrader{
+			// Connections are authenticated with the JWT, not with cookies, so foreign
+			// origins gain nothing and browser dashboards on other hosts keep working.
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// ServeHTTP authenticates the connection with the token of the Authorization header
+// ("Bearer <token>") or of the token query parameter, as browsers cannot set headers.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token = strings.TrimPrefix(header, "Bearer ")
+	}
+
+	username, err := h.authenticator.Username(token)
+	if err != nil {
+		http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	newConnection(h, conn, token, username).serve(r.Context())
+}
+
+type authenticator interface {
+	Username(token string) (string, error)
+}
+
+type chains interface {
+	ChainID(selector string) (uint64, error)
+}
+
+type events interface {
+	Subscribe() (<-chan model.Event, func())
+}
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = 30 * time.Second
+	maxMessageSize = 1 << 20
+)
+
+type handler struct {
+	rpcHandler    http.Handler
+	authenticator authenticator
+	chains        chains
+	events        events
+	upgrader      websocket.Upgrader
+}
@@ -0,0 +1,155 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/avalkov/eth-node-interaction/internal/chains"
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func NewSimulator(client client) *simulator {
+	return &simulator{client: client}
+}
+
+func (s *simulator) Simulate(ctx context.Context, request model.CallRequest) (model.Simulation, error) {
+	callArg, err := toCallArg(request)
+	if err != nil {
+		return model.Simulation{}, err
+	}
+
+	block, err := chains.BlockParam(request.Block)
+	if err != nil {
+		return model.Simulation{}, err
+	}
+
+	callParams := []interface{}{callArg, block}
+	if len(request.StateOverrides) > 0 {
+		callParams = append(callParams, request.StateOverrides)
+	}
+
+	var simulation model.Simulation
+
+	var returnData hexutil.Bytes
+	if err := s.client.CallContext(ctx, &returnData, "eth_call", callParams...); err != nil {
+		revertData, ok := revertDataOf(err)
+		if !ok {
+			return model.Simulation{}, fmt.Errorf("eth_call failed: %s", err)
+		}
+
+		message := err.Error()
+		simulation.Error = &message
+		simulation.ReturnData = hexutil.Encode(revertData)
+		if reason, ok := decodeRevert(revertData); ok {
+			simulation.RevertReason = &reason
+		}
+
+		return simulation, nil
+	}
+
+	simulation.Success = true
+	simulation.ReturnData = returnData.String()
+
+	// Overrides are not supported by eth_estimateGas, so the estimate reflects the unmodified state
+	var gasEstimate hexutil.Uint64
+	if err := s.client.CallContext(ctx, &gasEstimate, "eth_estimateGas", callArg, block); err != nil {
+		message := fmt.Sprintf("eth_estimateGas failed: %s", err)
+		simulation.Error = &message
+		return simulation, nil
+	}
+
+	estimate := uint64(gasEstimate)
+	simulation.GasEstimate = &estimate
+
+	return simulation, nil
+}
+
+func toCallArg(request model.CallRequest) (map[string]interface{}, error) {
+	arg := map[string]interface{}{}
+
+	if request.From != "" {
+		if !common.IsHexAddress(request.From) {
+			return nil, fmt.Errorf("invalid from address (%s)", request.From)
+		}
+		arg["from"] = common.HexToAddress(request.From)
+	}
+
+	if request.To != nil {
+		if !common.IsHexAddress(*request.To) {
+			return nil, fmt.Errorf("invalid to address (%s)", *request.To)
+		}
+		arg["to"] = common.HexToAddress(*request.To)
+	}
+
+	if request.Data != "" {
+		data, err := hexutil.Decode(request.Data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data: %s", err)
+		}
+		arg["data"] = hexutil.Bytes(data)
+	}
+
+	if request.Value != "" {
+		value, ok := new(big.Int).SetString(request.Value, 0)
+		if !ok || value.Sign() < 0 {
+			return nil, fmt.Errorf("invalid value (%s)", request.Value)
+		}
+		arg["value"] = (*hexutil.Big)(value)
+	}
+
+	return arg, nil
+}
+
+// Nodes report reverts as an error whose data field holds the revert payload.
+// Reverts without a payload only carry the "execution reverted" message.
+func revertDataOf(err error) ([]byte, bool) {
+	isRevert := strings.Contains(err.Error(), "execution reverted")
+
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return nil, isRevert
+	}
+
+	data, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return nil, isRevert
+	}
+
+	revertData, decodeErr := hexutil.Decode(data)
+	if decodeErr != nil {
+		return nil, isRevert
+	}
+
+	return revertData, true
+}
+
+func decodeRevert(data []byte) (string, bool) {
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		return reason, true
+	}
+
+	if len(data) == 36 && common.Bytes2Hex(data[:4]) == panicSelector {
+		code := new(big.Int).SetBytes(data[4:])
+		return fmt.Sprintf("panic: 0x%x", code), true
+	}
+
+	return "", false
+}
+
+// Selector of Panic(uint256) used by solidity for failed assertions, overflows, etc.
+const panicSelector = "4e487b71"
+
+type client interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+type simulator struct {
+	client client
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+)
+
+func (s *storage) GetContract(ctx context.Context, chainID uint64, address string) (*model.ContractInfo, error) {
+	var contracts []model.ContractInfo
+	if err := s.db.SelectContext(ctx, &contracts, s.db.Rebind(`SELECT * FROM contract WHERE chain_id = ? AND address = ?`),
+		chainID, address); err != nil {
+		return nil, err
+	}
+	if len(contracts) == 0 {
+		return nil, nil
+	}
+	return &contracts[0], nil
+}
+
+// StoreContract keeps the creation transaction once known.
+func (s *storage) StoreContract(ctx context.Context, contract model.ContractInfo) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`INSERT INTO contract (chain_id, address, code_hash, code_size, proxy_type, implementation,
+    beacon, admin, creation_transaction_hash, block_number, updated_at) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    ON CONFLICT (chain_id, address) DO UPDATE SET code_hash = EXCLUDED.code_hash, code_size = EXCLUDED.code_size,
+    proxy_type = EXCLUDED.proxy_type, implementation = EXCLUDED.implementation, beacon = EXCLUDED.beacon, admin = EXCLUDED.admin,
+    creation_transaction_hash = COALESCE(contract.creation_transaction_hash, EXCLUDED.creation_transaction_hash),
+    block_number = EXCLUDED.block_number, updated_at = EXCLUDED.updated_at`),
+		contract.ChainID, contract.Address, contract.CodeHash, contract.CodeSize, contract.ProxyType, contract.Implementation,
+		contract.Beacon, contract.Admin, contract.CreationTransactionHash, contract.BlockNumber, contract.UpdatedAt)
+	return err
+}
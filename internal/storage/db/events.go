@@ -0,0 +1,33 @@
+package db
+
+import (
+	"context"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+)
+
+func (s *storage) StoreEvent(ctx context.Context, event model.StoredEvent) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`INSERT INTO tx_event (username, chain_id, transaction_hash, event_type, payload, created_at)
+    VALUES(?, ?, ?, ?, ?, ?)`), event.Username, event.ChainID, event.TransactionHash, event.EventType, event.Payload, event.CreatedAt)
+	return err
+}
+
+func (s *storage) GetEventsAfter(ctx context.Context, username string, afterID int64, limit int) ([]model.StoredEvent, error) {
+	events := []model.StoredEvent{}
+	if err := s.db.SelectContext(ctx, &events, s.db.Rebind(`SELECT * FROM tx_event WHERE username = ? AND id > ? ORDER BY id LIMIT ?`),
+		username, afterID, limit); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (s *storage) GetLastEventID(ctx context.Context, username string) (int64, error) {
+	var id int64
+	err := s.db.GetContext(ctx, &id, s.db.Rebind(`SELECT COALESCE(MAX(id), 0) FROM tx_event WHERE username = ?`), username)
+	return id, err
+}
+
+func (s *storage) PruneEvents(ctx context.Context, before int64) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`DELETE FROM tx_event WHERE created_at < ?`), before)
+	return err
+}
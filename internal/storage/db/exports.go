@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+)
+
+// StreamTxs hands the matching transactions to fn one by one, ordered by block and index,
+// without loading them all in memory. Pending transactions come last.
+func (s *storage) StreamTxs(ctx context.Context, filter model.TxFilter, fn func(model.Transaction) error) error {
+	where, args := txFilterWhere(filter)
+
+	rows, err := s.db.QueryxContext(ctx, s.db.Rebind(`SELECT t.* FROM transaction AS t WHERE `+where+` 
+    ORDER BY `+txPositionOrder(false)), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transaction model.Transaction
+		if err := rows.StructScan(&transaction); err != nil {
+			return err
+		}
+		if err := fn(transaction); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+)
+
+// StoreImportedTxs inserts the transactions in one statement and returns how many were new.
+// Transactions that are already cached are kept as they are.
+func (s *storage) StoreImportedTxs(ctx context.Context, transactions []model.Transaction) (int64, error) {
+	if len(transactions) == 0 {
+		return 0, nil
+	}
+
+	rows := make([]string, 0, len(transactions))
+	args := make([]interface{}, 0, len(transactions)*16)
+	for _, transaction := range transactions {
+		rows = append(rows, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		args = append(args, transaction.ChainID, transaction.TransactionHash, transaction.TransactionStatus, transaction.BlockHash,
+			transaction.BlockNumber, transaction.TransactionIndex, transaction.From, transaction.To, transaction.ContractAddress, transaction.LogsCount, transaction.Input,
+			transaction.Value, transaction.Nonce, transaction.ReplacedBy, transaction.FirstSeenAt, transaction.Finalized)
+	}
+
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(`INSERT INTO transaction (chain_id, transaction_hash, transaction_status, block_hash, 
+    block_number, transaction_index, from_address, to_address, contract_address, logs_count, input, value, nonce, replaced_by, first_seen_at, finalized) 
+    VALUES `+strings.Join(rows, ", ")+` ON CONFLICT (chain_id, transaction_hash) DO NOTHING`), args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+)
+
+// ListTxs returns a page of the transactions after the position of the query. The sort
+// expressions match transaction_position_index.
+func (s *storage) ListTxs(ctx context.Context, query model.TxQuery) ([]model.Transaction, error) {
+	where, args := txFilterWhere(query.Filter)
+
+	from := "transaction AS t"
+	if query.Token != nil {
+		from += " INNER JOIN token_transaction AS tt ON t.chain_id = tt.chain_id AND t.transaction_hash = tt.transaction_hash"
+		where += " AND tt.token = ?"
+		args = append(args, *query.Token)
+	}
+
+	if query.After != nil {
+		comparison := ">"
+		if query.Descending {
+			comparison = "<"
+		}
+		where += fmt.Sprintf(" AND (%s, %s, t.transaction_hash) %s (?, ?, ?)", positionBlockExpr, positionIndexExpr, comparison)
+		args = append(args, query.After.BlockNumber, query.After.Index, query.After.Hash)
+	}
+
+	args = append(args, query.Limit)
+
+	var transactions []model.Transaction
+	if err := s.db.SelectContext(ctx, &transactions, s.db.Rebind(`SELECT t.* FROM `+from+` WHERE `+where+` 
+    ORDER BY `+txPositionOrder(query.Descending)+` LIMIT ?`), args...); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+func txFilterWhere(filter model.TxFilter) (string, []interface{}) {
+	conditions := []string{"t.chain_id = ?"}
+	args := []interface{}{filter.ChainID}
+
+	if filter.FromBlock != nil {
+		conditions = append(conditions, "t.block_number >= ?")
+		args = append(args, *filter.FromBlock)
+	}
+
+	if filter.ToBlock != nil {
+		conditions = append(conditions, "t.block_number <= ?")
+		args = append(args, *filter.ToBlock)
+	}
+
+	if filter.Address != nil {
+		conditions = append(conditions, "(t.from_address = ? OR t.to_address = ? OR t.contract_address = ?)")
+		args = append(args, *filter.Address, *filter.Address, *filter.Address)
+	}
+
+	if filter.From != nil {
+		conditions = append(conditions, "t.from_address = ?")
+		args = append(args, *filter.From)
+	}
+
+	if filter.To != nil {
+		conditions = append(conditions, "t.to_address = ?")
+		args = append(args, *filter.To)
+	}
+
+	if filter.ContractAddress != nil {
+		conditions = append(conditions, "t.contract_address = ?")
+		args = append(args, *filter.ContractAddress)
+	}
+
+	if filter.Status != nil {
+		conditions = append(conditions, "t.transaction_status = ?")
+		args = append(args, *filter.Status)
+	}
+
+	if filter.HasLogs != nil {
+		if *filter.HasLogs {
+			conditions = append(conditions, "t.logs_count > 0")
+		} else {
+			conditions = append(conditions, "COALESCE(t.logs_count, 0) = 0")
+		}
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+func txPositionOrder(descending bool) string {
+	direction := "ASC"
+	if descending {
+		direction = "DESC"
+	}
+	return fmt.Sprintf("%s %s, %s %s, t.transaction_hash %s", positionBlockExpr, direction, positionIndexExpr, direction, direction)
+}
+
+var (
+	positionBlockExpr = fmt.Sprintf("COALESCE(t.block_number, %d)", model.PendingBlockNumber)
+	positionIndexExpr = "COALESCE(t.transaction_index, -1)"
+)
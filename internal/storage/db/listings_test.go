@@ -0,0 +1,57 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+)
+
+func TestTxFilterWhere(t *testing.T) {
+	fromBlock, address, status, hasLogs := uint64(5), "0xaa", model.Successful, false
+
+	tests := []struct {
+		name      string
+		filter    model.TxFilter
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{"chain only", model.TxFilter{ChainID: 1}, "t.chain_id = ?", []interface{}{uint64(1)}},
+		{
+			"address, block and status",
+			model.TxFilter{ChainID: 1, FromBlock: &fromBlock, Address: &address, Status: &status},
+			"t.chain_id = ? AND t.block_number >= ? AND (t.from_address = ? OR t.to_address = ? OR t.contract_address = ?) AND t.transaction_status = ?",
+			[]interface{}{uint64(1), fromBlock, address, address, address, status},
+		},
+		{"without logs", model.TxFilter{ChainID: 1, HasLogs: &hasLogs}, "t.chain_id = ? AND COALESCE(t.logs_count, 0) = 0", []interface{}{uint64(1)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			where, args := txFilterWhere(test.filter)
+			if where != test.wantWhere {
+				t.Fatalf("where = %s, want %s", where, test.wantWhere)
+			}
+			if !reflect.DeepEqual(args, test.wantArgs) {
+				t.Fatalf("args = %v, want %v", args, test.wantArgs)
+			}
+		})
+	}
+}
+
+// The order has to match the position compared against the cursor, pending transactions last.
+func TestTxPositionOrder(t *testing.T) {
+	tests := []struct {
+		descending bool
+		want       string
+	}{
+		{false, "COALESCE(t.block_number, 2147483647) ASC, COALESCE(t.transaction_index, -1) ASC, t.transaction_hash ASC"},
+		{true, "COALESCE(t.block_number, 2147483647) DESC, COALESCE(t.transaction_index, -1) DESC, t.transaction_hash DESC"},
+	}
+
+	for _, test := range tests {
+		if got := txPositionOrder(test.descending); got != test.want {
+			t.Errorf("txPositionOrder(%t) = %s, want %s", test.descending, got, test.want)
+		}
+	}
+}
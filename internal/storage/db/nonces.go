@@ -0,0 +1,210 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// ReserveNonce hands out the lowest released nonce that is still usable, otherwise
+// the next nonce of the account. The account row is locked for the duration of the call.
+func (s *storage) ReserveNonce(ctx context.Context, chainID uint64, address, username string, pendingNonce uint64) (uint64, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		tx.Rollback()
+	}()
+
+	nextNonce, err := s.lockNonceAccount(ctx, tx, chainID, address, pendingNonce)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UnixNano()
+
+	var releasedNonce uint64
+	err = tx.GetContext(ctx, &releasedNonce, s.db.Rebind(`SELECT nonce FROM nonce_reservation WHERE chain_id = ? AND address = ?
+    AND status = ? AND nonce >= ? ORDER BY nonce LIMIT 1`), chainID, address, model.NonceReleased, pendingNonce)
+	if err == nil {
+		if _, err := tx.ExecContext(ctx, s.db.Rebind(`UPDATE nonce_reservation SET status = ?, transaction_hash = NULL, reserved_by = ?,
+    updated_at = ? WHERE chain_id = ? AND address = ? AND nonce = ?`), model.NonceReserved, username, now, chainID, address, releasedNonce); err != nil {
+			return 0, err
+		}
+		return releasedNonce, tx.Commit()
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	nonce := nextNonce
+	if pendingNonce > nonce {
+		nonce = pendingNonce
+	}
+
+	if _, err := tx.ExecContext(ctx, s.db.Rebind(`INSERT INTO nonce_reservation (chain_id, address, nonce, status, transaction_hash, reserved_by, updated_at)
+    VALUES(?, ?, ?, ?, NULL, ?, ?) ON CONFLICT (chain_id, address, nonce) DO UPDATE SET status = EXCLUDED.status,
+    transaction_hash = NULL, reserved_by = EXCLUDED.reserved_by, updated_at = EXCLUDED.updated_at`),
+		chainID, address, nonce, model.NonceReserved, username, now); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, s.db.Rebind(`UPDATE nonce_account SET next_nonce = ? WHERE chain_id = ? AND address = ?`),
+		nonce+1, chainID, address); err != nil {
+		return 0, err
+	}
+
+	return nonce, tx.Commit()
+}
+
+// ReleaseNonce only releases reservations of the user and reports whether there was one.
+func (s *storage) ReleaseNonce(ctx context.Context, chainID uint64, address, username string, nonce uint64) (bool, error) {
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE nonce_reservation SET status = ?, updated_at = ?
+    WHERE chain_id = ? AND address = ? AND nonce = ? AND status = ? AND reserved_by = ?`),
+		model.NonceReleased, time.Now().UnixNano(), chainID, address, nonce, model.NonceReserved, username)
+	if err != nil {
+		return false, err
+	}
+
+	released, err := result.RowsAffected()
+	return released > 0, err
+}
+
+func (s *storage) MarkNonceSent(ctx context.Context, chainID uint64, address string, nonce uint64, txHash string) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE nonce_reservation SET status = ?, transaction_hash = ?, updated_at = ?
+    WHERE chain_id = ? AND address = ? AND nonce = ?`),
+		model.NonceSent, txHash, time.Now().UnixNano(), chainID, address, nonce)
+	return err
+}
+
+// ClaimNonce reserves a nonce chosen by the sender for the transaction. It fails while another
+// transaction or another user holds the nonce, unless its reservation was abandoned before staleBefore.
+func (s *storage) ClaimNonce(ctx context.Context, chainID uint64, address, username string, nonce, pendingNonce uint64, txHash string, staleBefore int64) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		tx.Rollback()
+	}()
+
+	nextNonce, err := s.lockNonceAccount(ctx, tx, chainID, address, pendingNonce)
+	if err != nil {
+		return err
+	}
+
+	var reservation model.NonceReservation
+	err = tx.GetContext(ctx, &reservation, s.db.Rebind(`SELECT * FROM nonce_reservation WHERE chain_id = ? AND address = ? AND nonce = ?`),
+		chainID, address, nonce)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if err == nil {
+		if err := claimable(reservation, username, txHash, staleBefore); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, s.db.Rebind(`INSERT INTO nonce_reservation (chain_id, address, nonce, status, transaction_hash, reserved_by, updated_at)
+    VALUES(?, ?, ?, ?, ?, ?, ?) ON CONFLICT (chain_id, address, nonce) DO UPDATE SET status = EXCLUDED.status,
+    transaction_hash = EXCLUDED.transaction_hash, reserved_by = EXCLUDED.reserved_by, updated_at = EXCLUDED.updated_at`),
+		chainID, address, nonce, model.NonceReserved, txHash, username, time.Now().UnixNano()); err != nil {
+		return err
+	}
+
+	if nonce >= nextNonce {
+		if _, err := tx.ExecContext(ctx, s.db.Rebind(`UPDATE nonce_account SET next_nonce = ? WHERE chain_id = ? AND address = ?`),
+			nonce+1, chainID, address); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// claimable lets the transaction take the nonce when it was released, abandoned, sent with the same
+// transaction or reserved by the same user. Users may replace the transactions they sent.
+func claimable(reservation model.NonceReservation, username, txHash string, staleBefore int64) error {
+	sameTx := reservation.TransactionHash != nil && *reservation.TransactionHash == txHash
+	sameUser := reservation.ReservedBy != nil && *reservation.ReservedBy == username
+
+	switch {
+	case sameTx || reservation.Status == model.NonceReleased:
+		return nil
+	case reservation.Status == model.NonceSent && sameUser:
+		return nil
+	case reservation.Status == model.NonceSent:
+		return fmt.Errorf("nonce %d of (%s) is used by tx (%s)", reservation.Nonce, reservation.Address, *reservation.TransactionHash)
+	case reservation.UpdatedAt < staleBefore:
+		return nil
+	case reservation.TransactionHash != nil:
+		return fmt.Errorf("nonce %d of (%s) is used by tx (%s)", reservation.Nonce, reservation.Address, *reservation.TransactionHash)
+	case !sameUser:
+		return fmt.Errorf("nonce %d of (%s) is reserved by another user", reservation.Nonce, reservation.Address)
+	}
+
+	return nil
+}
+
+// ReconcileNonces drops reservations that were mined and moves the next nonce past
+// anything the node already knows about. It returns the next nonce and the remaining reservations.
+func (s *storage) ReconcileNonces(ctx context.Context, chainID uint64, address string, minedNonce, pendingNonce uint64) (uint64, []model.NonceReservation, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	defer func() {
+		tx.Rollback()
+	}()
+
+	nextNonce, err := s.lockNonceAccount(ctx, tx, chainID, address, pendingNonce)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, s.db.Rebind(`DELETE FROM nonce_reservation WHERE chain_id = ? AND address = ? AND nonce < ?`),
+		chainID, address, minedNonce); err != nil {
+		return 0, nil, err
+	}
+
+	if pendingNonce > nextNonce {
+		nextNonce = pendingNonce
+		if _, err := tx.ExecContext(ctx, s.db.Rebind(`UPDATE nonce_account SET next_nonce = ? WHERE chain_id = ? AND address = ?`),
+			nextNonce, chainID, address); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	var reservations []model.NonceReservation
+	if err := tx.SelectContext(ctx, &reservations, s.db.Rebind(`SELECT * FROM nonce_reservation WHERE chain_id = ? AND address = ?
+    ORDER BY nonce`), chainID, address); err != nil {
+		return 0, nil, err
+	}
+
+	return nextNonce, reservations, tx.Commit()
+}
+
+func (s *storage) lockNonceAccount(ctx context.Context, tx *sqlx.Tx, chainID uint64, address string, initialNonce uint64) (uint64, error) {
+	if _, err := tx.ExecContext(ctx, s.db.Rebind(`INSERT INTO nonce_account (chain_id, address, next_nonce) VALUES(?, ?, ?)
+    ON CONFLICT DO NOTHING`), chainID, address, initialNonce); err != nil {
+		return 0, err
+	}
+
+	var nextNonce uint64
+	if err := tx.GetContext(ctx, &nextNonce, s.db.Rebind(`SELECT next_nonce FROM nonce_account WHERE chain_id = ? AND address = ?
+    FOR UPDATE`), chainID, address); err != nil {
+		return 0, err
+	}
+
+	return nextNonce, nil
+}
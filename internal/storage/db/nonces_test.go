@@ -0,0 +1,43 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+)
+
+func TestClaimable(t *testing.T) {
+	const staleBefore = 100
+	alice, txHash, otherHash := "alice", "0xaa", "0xbb"
+
+	tests := []struct {
+		name        string
+		reservation model.NonceReservation
+		wantErr     bool
+	}{
+		{"fresh reservation of the user", model.NonceReservation{Status: model.NonceReserved, ReservedBy: &alice, UpdatedAt: 200}, false},
+		{"fresh reservation of another user", model.NonceReservation{Status: model.NonceReserved, ReservedBy: strPtr("bob"), UpdatedAt: 200}, true},
+		{"fresh reservation without a user", model.NonceReservation{Status: model.NonceReserved, UpdatedAt: 200}, true},
+		{"stale reservation of another user", model.NonceReservation{Status: model.NonceReserved, ReservedBy: strPtr("bob"), UpdatedAt: 50}, false},
+		{"fresh claim of another tx", model.NonceReservation{Status: model.NonceReserved, ReservedBy: &alice, TransactionHash: &otherHash, UpdatedAt: 200}, true},
+		{"stale claim of another tx", model.NonceReservation{Status: model.NonceReserved, ReservedBy: strPtr("bob"), TransactionHash: &otherHash, UpdatedAt: 50}, false},
+		{"claim of the same tx", model.NonceReservation{Status: model.NonceReserved, ReservedBy: strPtr("bob"), TransactionHash: &txHash, UpdatedAt: 200}, false},
+		{"sent by the user with another tx", model.NonceReservation{Status: model.NonceSent, ReservedBy: &alice, TransactionHash: &otherHash, UpdatedAt: 200}, false},
+		{"sent by another user with another tx", model.NonceReservation{Status: model.NonceSent, ReservedBy: strPtr("bob"), TransactionHash: &otherHash, UpdatedAt: 50}, true},
+		{"sent with the same tx", model.NonceReservation{Status: model.NonceSent, TransactionHash: &txHash, UpdatedAt: 200}, false},
+		{"released by another user", model.NonceReservation{Status: model.NonceReleased, ReservedBy: strPtr("bob"), UpdatedAt: 200}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := claimable(test.reservation, alice, txHash, staleBefore)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("claimable = %v, want error %t", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func strPtr(value string) *string {
+	return &value
+}
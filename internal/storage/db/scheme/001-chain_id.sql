@@ -0,0 +1,12 @@
+ALTER TABLE token_transaction DROP CONSTRAINT token_transaction_transaction_hash_fkey;
+ALTER TABLE token_transaction DROP CONSTRAINT token_transaction_pkey;
+ALTER TABLE transaction DROP CONSTRAINT transaction_pkey;
+
+/* Rows cached before multi-chain support get chain 0 and are assigned to the default chain on startup */
+ALTER TABLE transaction ADD COLUMN chain_id BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE token_transaction ADD COLUMN chain_id BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE transaction ADD PRIMARY KEY (chain_id, transaction_hash);
+ALTER TABLE token_transaction ADD PRIMARY KEY (chain_id, token, transaction_hash);
+ALTER TABLE token_transaction ADD FOREIGN KEY (chain_id, transaction_hash)
+    REFERENCES transaction (chain_id, transaction_hash) ON UPDATE CASCADE;
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE transaction ALTER COLUMN block_hash DROP NOT NULL;
+ALTER TABLE transaction ALTER COLUMN block_number DROP NOT NULL;
+ALTER TABLE transaction ALTER COLUMN logs_count DROP NOT NULL;
+
+CREATE INDEX transaction_pending_index ON transaction (chain_id) WHERE transaction_status = 2;
\ No newline at end of file
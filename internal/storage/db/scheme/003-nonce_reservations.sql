@@ -0,0 +1,19 @@
+CREATE TABLE nonce_account
+(
+    chain_id BIGINT NOT NULL,
+    address TEXT NOT NULL,
+    next_nonce BIGINT NOT NULL,
+    PRIMARY KEY (chain_id, address)
+);
+
+CREATE TABLE nonce_reservation
+(
+    chain_id BIGINT NOT NULL,
+    address TEXT NOT NULL,
+    nonce BIGINT NOT NULL,
+    status INT NOT NULL,
+    transaction_hash TEXT,
+    updated_at BIGINT NOT NULL,
+    PRIMARY KEY (chain_id, address, nonce),
+    FOREIGN KEY (chain_id, address) REFERENCES nonce_account (chain_id, address)
+);
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE account_state
+(
+    chain_id BIGINT NOT NULL,
+    address TEXT NOT NULL,
+    block_number BIGINT NOT NULL,
+    balance TEXT NOT NULL,
+    nonce BIGINT NOT NULL,
+    code_hash TEXT NOT NULL,
+    PRIMARY KEY (chain_id, address, block_number)
+);
\ No newline at end of file
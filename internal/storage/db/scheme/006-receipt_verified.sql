@@ -0,0 +1 @@
+ALTER TABLE transaction ADD COLUMN receipt_verified BOOLEAN NOT NULL DEFAULT FALSE;
\ No newline at end of file
@@ -0,0 +1,29 @@
+CREATE TABLE watched_address
+(
+    id SERIAL PRIMARY KEY NOT NULL,
+    username TEXT NOT NULL REFERENCES users (username),
+    chain_id BIGINT NOT NULL,
+    address TEXT NOT NULL,
+    created_at BIGINT NOT NULL,
+    UNIQUE (username, chain_id, address)
+);
+
+CREATE INDEX watched_address_chain_index ON watched_address (chain_id);
+
+CREATE TABLE notification
+(
+    id BIGSERIAL PRIMARY KEY NOT NULL,
+    username TEXT NOT NULL REFERENCES users (username),
+    chain_id BIGINT NOT NULL,
+    address TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    transaction_hash TEXT NOT NULL,
+    block_number BIGINT NOT NULL,
+    /* -1 for transaction matches */
+    log_index INT NOT NULL,
+    created_at BIGINT NOT NULL,
+    acknowledged BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (username, chain_id, address, transaction_hash, log_index)
+);
+
+CREATE INDEX notification_inbox_index ON notification (username, acknowledged, id);
\ No newline at end of file
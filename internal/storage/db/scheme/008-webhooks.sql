@@ -0,0 +1,46 @@
+/* Tokens are short lived, the username lets events reach the user behind them */
+ALTER TABLE token_transaction ADD COLUMN username TEXT REFERENCES users (username);
+
+CREATE INDEX token_transaction_hash_index ON token_transaction (chain_id, transaction_hash);
+
+CREATE TABLE webhook_subscription
+(
+    id SERIAL PRIMARY KEY NOT NULL,
+    username TEXT NOT NULL REFERENCES users (username),
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    event_types TEXT NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX webhook_subscription_username_index ON webhook_subscription (username);
+
+CREATE TABLE webhook_delivery
+(
+    id BIGSERIAL PRIMARY KEY NOT NULL,
+    subscription_id INT NOT NULL REFERENCES webhook_subscription (id) ON DELETE CASCADE,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status INT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at BIGINT NOT NULL,
+    last_error TEXT,
+    created_at BIGINT NOT NULL,
+    delivered_at BIGINT
+);
+
+CREATE INDEX webhook_delivery_due_index ON webhook_delivery (next_attempt_at) WHERE status = 0;
+CREATE INDEX webhook_delivery_subscription_index ON webhook_delivery (subscription_id, id);
+
+CREATE TABLE webhook_delivery_attempt
+(
+    id BIGSERIAL PRIMARY KEY NOT NULL,
+    delivery_id BIGINT NOT NULL REFERENCES webhook_delivery (id) ON DELETE CASCADE,
+    attempt INT NOT NULL,
+    status_code INT,
+    error TEXT,
+    duration_ms BIGINT NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX webhook_delivery_attempt_delivery_index ON webhook_delivery_attempt (delivery_id);
\ No newline at end of file
@@ -0,0 +1,20 @@
+/* Mined transactions are followed until enough confirmations are reached, existing ones are considered final */
+ALTER TABLE transaction ADD COLUMN finalized BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE transaction SET finalized = TRUE WHERE transaction_status IN (0, 1);
+
+CREATE INDEX transaction_unfinalized_index ON transaction (chain_id) WHERE NOT finalized AND transaction_status IN (0, 1);
+
+CREATE TABLE tx_event
+(
+    id BIGSERIAL PRIMARY KEY NOT NULL,
+    username TEXT NOT NULL REFERENCES users (username),
+    chain_id BIGINT NOT NULL,
+    transaction_hash TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX tx_event_username_index ON tx_event (username, id);
+CREATE INDEX tx_event_created_at_index ON tx_event (created_at);
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE transaction ADD COLUMN nonce BIGINT;
+ALTER TABLE transaction ADD COLUMN replaced_by TEXT;
+
+CREATE INDEX transaction_sender_nonce_index ON transaction (chain_id, from_address, nonce);
\ No newline at end of file
@@ -0,0 +1,2 @@
+/* First time the transaction was seen in the mempool, to measure the time to inclusion */
+ALTER TABLE transaction ADD COLUMN first_seen_at BIGINT;
\ No newline at end of file
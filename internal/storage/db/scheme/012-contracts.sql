@@ -0,0 +1,19 @@
+CREATE TABLE contract
+(
+    chain_id BIGINT NOT NULL,
+    address TEXT NOT NULL,
+    code_hash TEXT NOT NULL,
+    code_size INT NOT NULL,
+    proxy_type TEXT NOT NULL,
+    implementation TEXT,
+    beacon TEXT,
+    admin TEXT,
+    creation_transaction_hash TEXT,
+    /* Block the code and proxy slots were read at */
+    block_number BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    PRIMARY KEY (chain_id, address)
+);
+
+CREATE INDEX contract_code_hash_index ON contract (chain_id, code_hash);
+CREATE INDEX contract_implementation_index ON contract (chain_id, implementation);
\ No newline at end of file
@@ -0,0 +1,7 @@
+/* Position of the transaction in its block, listings are ordered by block number and this index */
+ALTER TABLE transaction ADD COLUMN transaction_index INT;
+
+/* Pending transactions have no position and sort after mined ones, the listing queries repeat these expressions */
+CREATE INDEX transaction_position_index ON transaction (chain_id, COALESCE(block_number, 2147483647), COALESCE(transaction_index, -1), transaction_hash);
+CREATE INDEX transaction_to_address_index ON transaction (chain_id, to_address);
+CREATE INDEX transaction_contract_address_index ON transaction (chain_id, contract_address);
\ No newline at end of file
@@ -0,0 +1,2 @@
+/* User that reserved the nonce, only they may claim or release it while the reservation is fresh */
+ALTER TABLE nonce_reservation ADD COLUMN reserved_by TEXT;
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
@@ -23,64 +24,171 @@ func (s *storage) ExecuteMigrations(ctx context.Context) error {
 	return s.executeMigrations(ctx, s.db)
 }
 
-func (s *storage) GetTx(ctx context.Context, hash string) (model.Transaction, error) {
-	var transactions []model.Transaction
-	if err := s.db.SelectContext(ctx, &transactions, s.db.Rebind(`SELECT * FROM transaction WHERE transaction_hash = ?`), hash); err != nil {
-		return model.Transaction{}, err
+func (s *storage) AssignLegacyTxs(ctx context.Context, chainID uint64) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE transaction SET chain_id = ? WHERE chain_id = 0`), chainID)
+	return err
+}
+
+// GetTxs returns the cached transactions among the hashes, the others are left out.
+func (s *storage) GetTxs(ctx context.Context, chainID uint64, hashes []string) ([]model.Transaction, error) {
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := sqlx.In(`SELECT * FROM transaction WHERE chain_id = ? AND transaction_hash IN (?)`, chainID, hashes)
+	if err != nil {
+		return nil, err
 	}
-	if len(transactions) == 0 {
-		return model.Transaction{}, fmt.Errorf("tx (%s) not found.", hash)
+
+	var transactions []model.Transaction
+	if err := s.db.SelectContext(ctx, &transactions, s.db.Rebind(query), args...); err != nil {
+		return nil, err
 	}
-	return transactions[0], nil
+	return transactions, nil
 }
 
-func (s *storage) StoreTx(ctx context.Context, transaction model.Transaction, token *string) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+// AddTxOwner lists a cached transaction among the transactions requested with the token.
+func (s *storage) AddTxOwner(ctx context.Context, chainID uint64, hash string, owner model.TxOwner) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`INSERT INTO token_transaction (chain_id, token, transaction_hash, username) VALUES(?, ?, ?, ?) 
+    ON CONFLICT DO NOTHING`), chainID, owner.Token, hash, owner.Username)
+	return err
+}
+
+// StoreTx returns the status the transaction had before, nil when it was not stored yet. Only pending
+// and dropped transactions are updated.
+func (s *storage) StoreTx(ctx context.Context, transaction model.Transaction, owner *model.TxOwner) (*model.TxStatus, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	defer func() {
 		tx.Rollback()
 	}()
 
-	if _, err := tx.ExecContext(ctx, s.db.Rebind(`INSERT INTO transaction (transaction_hash, transaction_status, block_hash, block_number,
-    from_address, to_address, contract_address, logs_count, input, value) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING`),
-		transaction.TransactionHash, transaction.TransactionStatus, transaction.BlockHash, transaction.BlockNumber,
-		transaction.From, transaction.To, transaction.ContractAddress,
-		transaction.LogsCount, transaction.Input, transaction.Value); err != nil {
-		return err
+	var previousStatus *model.TxStatus
+	var status model.TxStatus
+	err = tx.GetContext(ctx, &status, s.db.Rebind(`SELECT transaction_status FROM transaction WHERE chain_id = ? AND transaction_hash = ?
+    FOR UPDATE`), transaction.ChainID, transaction.TransactionHash)
+	if err == nil {
+		previousStatus = &status
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
 	}
 
-	if token != nil {
-		if _, err := tx.ExecContext(ctx, s.db.Rebind(`INSERT INTO token_transaction (token, transaction_hash) VALUES(?, ?) 
-    ON CONFLICT DO NOTHING`), *token, transaction.TransactionHash); err != nil {
-			return err
+	if _, err := tx.ExecContext(ctx, s.db.Rebind(`INSERT INTO transaction (chain_id, transaction_hash, transaction_status, block_hash, block_number,
+    transaction_index, from_address, to_address, contract_address, logs_count, input, value, verified, receipt_verified, nonce, 
+    first_seen_at) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    ON CONFLICT (chain_id, transaction_hash) DO UPDATE SET transaction_status = EXCLUDED.transaction_status,
+    block_hash = EXCLUDED.block_hash, block_number = EXCLUDED.block_number, transaction_index = EXCLUDED.transaction_index,
+    contract_address = EXCLUDED.contract_address, logs_count = EXCLUDED.logs_count, verified = EXCLUDED.verified, receipt_verified = EXCLUDED.receipt_verified,
+    nonce = COALESCE(transaction.nonce, EXCLUDED.nonce), first_seen_at = COALESCE(transaction.first_seen_at, EXCLUDED.first_seen_at) WHERE transaction.transaction_status IN (?, ?)`),
+		transaction.ChainID, transaction.TransactionHash, transaction.TransactionStatus, transaction.BlockHash, transaction.BlockNumber,
+		transaction.TransactionIndex, transaction.From, transaction.To, transaction.ContractAddress, transaction.LogsCount, transaction.Input, transaction.Value,
+		transaction.Verified, transaction.ReceiptVerified, transaction.Nonce, transaction.FirstSeenAt, model.Pending, model.Dropped); err != nil {
+		return nil, err
+	}
+
+	if owner != nil {
+		if _, err := tx.ExecContext(ctx, s.db.Rebind(`INSERT INTO token_transaction (chain_id, token, transaction_hash, username) VALUES(?, ?, ?, ?) 
+    ON CONFLICT DO NOTHING`), transaction.ChainID, owner.Token, transaction.TransactionHash, owner.Username); err != nil {
+			return nil, err
 		}
 	}
 
-	return tx.Commit()
+	return previousStatus, tx.Commit()
 }
 
-func (s *storage) GetAllTxs(ctx context.Context) ([]model.Transaction, error) {
+func (s *storage) GetPendingTxs(ctx context.Context, chainID uint64) ([]model.Transaction, error) {
 	var transactions []model.Transaction
-	if err := s.db.SelectContext(ctx, &transactions, `SELECT * FROM transaction`); err != nil {
+	if err := s.db.SelectContext(ctx, &transactions, s.db.Rebind(`SELECT * FROM transaction WHERE chain_id = ? AND transaction_status = ?`),
+		chainID, model.Pending); err != nil {
 		return nil, err
 	}
 	return transactions, nil
 }
 
-func (s *storage) GetTxsByToken(ctx context.Context, token string) ([]model.Transaction, error) {
+func (s *storage) GetTxOwners(ctx context.Context, chainID uint64, hash string) ([]string, error) {
+	var usernames []string
+	if err := s.db.SelectContext(ctx, &usernames, s.db.Rebind(`SELECT DISTINCT username FROM token_transaction 
+    WHERE chain_id = ? AND transaction_hash = ? AND username IS NOT NULL`), chainID, hash); err != nil {
+		return nil, err
+	}
+	return usernames, nil
+}
+
+func (s *storage) UpdateTxStatus(ctx context.Context, chainID uint64, hash string, status model.TxStatus) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE transaction SET transaction_status = ? WHERE chain_id = ? AND transaction_hash = ?`),
+		status, chainID, hash)
+	return err
+}
+
+func (s *storage) GetUnfinalizedTxs(ctx context.Context, chainID uint64) ([]model.Transaction, error) {
 	var transactions []model.Transaction
-	if err := s.db.SelectContext(ctx, &transactions, s.db.Rebind(`SELECT t.transaction_hash, t.transaction_status, 
-    t.block_hash, t.block_number, t.from_address, t.to_address, t.contract_address, t.logs_count, t.input, 
-    t.value FROM transaction AS t INNER JOIN token_transaction AS tt ON 
-    t.transaction_hash = tt.transaction_hash WHERE tt.token = ?`), token); err != nil {
+	if err := s.db.SelectContext(ctx, &transactions, s.db.Rebind(`SELECT * FROM transaction WHERE chain_id = ? AND NOT finalized 
+    AND transaction_status IN (?, ?)`), chainID, model.Failed, model.Successful); err != nil {
 		return nil, err
 	}
 	return transactions, nil
 }
 
+func (s *storage) FinalizeTx(ctx context.Context, chainID uint64, hash string) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE transaction SET finalized = TRUE WHERE chain_id = ? AND transaction_hash = ?`),
+		chainID, hash)
+	return err
+}
+
+// ReorgTx puts a transaction whose block left the canonical chain back to pending.
+func (s *storage) ReorgTx(ctx context.Context, chainID uint64, hash string) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE transaction SET transaction_status = ?, block_hash = NULL, block_number = NULL,
+    transaction_index = NULL, contract_address = NULL, logs_count = NULL, verified = FALSE, receipt_verified = FALSE WHERE chain_id = ? AND transaction_hash = ?`),
+		model.Pending, chainID, hash)
+	return err
+}
+
+// MarkTxReplaced reports whether the transaction was still pending or dropped.
+func (s *storage) MarkTxReplaced(ctx context.Context, chainID uint64, hash string, replacedBy *string) (bool, error) {
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE transaction SET transaction_status = ?, replaced_by = ? WHERE chain_id = ? 
+    AND transaction_hash = ? AND transaction_status IN (?, ?)`), model.Replaced, replacedBy, chainID, hash, model.Pending, model.Dropped)
+	if err != nil {
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	return updated > 0, err
+}
+
+// GetMinedTxHashByNonce returns nil when no mined transaction of the sender with the nonce is stored.
+func (s *storage) GetMinedTxHashByNonce(ctx context.Context, chainID uint64, from string, nonce uint64) (*string, error) {
+	var hashes []string
+	if err := s.db.SelectContext(ctx, &hashes, s.db.Rebind(`SELECT transaction_hash FROM transaction WHERE chain_id = ? AND from_address = ? 
+    AND nonce = ? AND transaction_status IN (?, ?) LIMIT 1`), chainID, from, nonce, model.Failed, model.Successful); err != nil {
+		return nil, err
+	}
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	return &hashes[0], nil
+}
+
+func (s *storage) GetAccountState(ctx context.Context, chainID uint64, address string, blockNumber uint64) (*model.AccountState, error) {
+	var states []model.AccountState
+	if err := s.db.SelectContext(ctx, &states, s.db.Rebind(`SELECT * FROM account_state WHERE chain_id = ? AND address = ? 
+    AND block_number = ?`), chainID, address, blockNumber); err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, nil
+	}
+	return &states[0], nil
+}
+
+func (s *storage) StoreAccountState(ctx context.Context, state model.AccountState) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`INSERT INTO account_state (chain_id, address, block_number, balance, nonce, code_hash)
+    VALUES(?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING`),
+		state.ChainID, state.Address, state.BlockNumber, state.Balance, state.Nonce, state.CodeHash)
+	return err
+}
+
 func (s *storage) IsUserExisting(ctx context.Context, username, password string) error {
 	row := s.db.QueryRowContext(ctx, s.db.Rebind(`SELECT COUNT(*) FROM users WHERE username = ? AND password = ?`), username, password)
 	errNotFound := errors.New("user not found")
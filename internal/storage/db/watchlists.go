@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+func (s *storage) WatchAddress(ctx context.Context, username string, chainID uint64, address string) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`INSERT INTO watched_address (username, chain_id, address, created_at) VALUES(?, ?, ?, ?)
+    ON CONFLICT DO NOTHING`), username, chainID, address, time.Now().UnixNano())
+	return err
+}
+
+func (s *storage) UnwatchAddress(ctx context.Context, username string, chainID uint64, address string) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`DELETE FROM watched_address WHERE username = ? AND chain_id = ? AND address = ?`),
+		username, chainID, address)
+	return err
+}
+
+func (s *storage) GetWatchedAddressesByUser(ctx context.Context, username string, chainID uint64) ([]model.WatchedAddress, error) {
+	watched := []model.WatchedAddress{}
+	if err := s.db.SelectContext(ctx, &watched, s.db.Rebind(`SELECT * FROM watched_address WHERE username = ? AND chain_id = ?
+    ORDER BY id`), username, chainID); err != nil {
+		return nil, err
+	}
+	return watched, nil
+}
+
+func (s *storage) GetWatchedAddresses(ctx context.Context, chainID uint64) ([]model.WatchedAddress, error) {
+	var watched []model.WatchedAddress
+	if err := s.db.SelectContext(ctx, &watched, s.db.Rebind(`SELECT * FROM watched_address WHERE chain_id = ?`), chainID); err != nil {
+		return nil, err
+	}
+	return watched, nil
+}
+
+// StoreNotifications returns the notifications that were not recorded before.
+func (s *storage) StoreNotifications(ctx context.Context, notifications []model.Notification) ([]model.Notification, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		tx.Rollback()
+	}()
+
+	stored := []model.Notification{}
+	for _, notification := range notifications {
+		var ids []int64
+		if err := tx.SelectContext(ctx, &ids, s.db.Rebind(`INSERT INTO notification (username, chain_id, address, kind, transaction_hash,
+    block_number, log_index, created_at) VALUES(?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING RETURNING id`),
+			notification.Username, notification.ChainID, notification.Address, notification.Kind, notification.TransactionHash,
+			notification.BlockNumber, notification.LogIndex, notification.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		if len(ids) > 0 {
+			notification.ID = ids[0]
+			stored = append(stored, notification)
+		}
+	}
+
+	return stored, tx.Commit()
+}
+
+func (s *storage) GetNotifications(ctx context.Context, username string, includeAcknowledged bool, limit int) ([]model.Notification, error) {
+	notifications := []model.Notification{}
+	if err := s.db.SelectContext(ctx, &notifications, s.db.Rebind(`SELECT * FROM notification WHERE username = ?
+    AND (? OR NOT acknowledged) ORDER BY id DESC LIMIT ?`), username, includeAcknowledged, limit); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (s *storage) AcknowledgeNotifications(ctx context.Context, username string, ids []int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	query, args, err := sqlx.In(`UPDATE notification SET acknowledged = TRUE WHERE username = ? AND id IN (?)`, username, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
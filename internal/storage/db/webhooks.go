@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+)
+
+func (s *storage) CreateWebhook(ctx context.Context, subscription model.WebhookSubscription) (model.WebhookSubscription, error) {
+	subscription.CreatedAt = time.Now().UnixNano()
+	err := s.db.GetContext(ctx, &subscription.ID, s.db.Rebind(`INSERT INTO webhook_subscription (username, url, secret, event_types, created_at)
+    VALUES(?, ?, ?, ?, ?) RETURNING id`), subscription.Username, subscription.Url, subscription.Secret, subscription.EventTypes, subscription.CreatedAt)
+	return subscription, err
+}
+
+func (s *storage) DeleteWebhook(ctx context.Context, username string, id int64) (bool, error) {
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(`DELETE FROM webhook_subscription WHERE username = ? AND id = ?`), username, id)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+func (s *storage) GetWebhooks(ctx context.Context, username string) ([]model.WebhookSubscription, error) {
+	subscriptions := []model.WebhookSubscription{}
+	if err := s.db.SelectContext(ctx, &subscriptions, s.db.Rebind(`SELECT * FROM webhook_subscription WHERE username = ? ORDER BY id`),
+		username); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (s *storage) EnqueueDelivery(ctx context.Context, delivery model.WebhookDelivery) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`INSERT INTO webhook_delivery (subscription_id, event_type, payload, status, attempts,
+    next_attempt_at, created_at) VALUES(?, ?, ?, ?, 0, ?, ?)`),
+		delivery.SubscriptionID, delivery.EventType, delivery.Payload, model.DeliveryPending, delivery.NextAttemptAt, delivery.CreatedAt)
+	return err
+}
+
+// ClaimDueDeliveries pushes the next attempt of the claimed deliveries forward by the lease,
+// so they are not picked up again while being sent and are retried if the sender dies.
+func (s *storage) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]model.WebhookDelivery, []model.WebhookSubscription, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer func() {
+		tx.Rollback()
+	}()
+
+	now := time.Now()
+
+	var deliveries []model.WebhookDelivery
+	if err := tx.SelectContext(ctx, &deliveries, s.db.Rebind(`SELECT * FROM webhook_delivery WHERE status = ? AND next_attempt_at <= ?
+    ORDER BY next_attempt_at LIMIT ? FOR UPDATE SKIP LOCKED`), model.DeliveryPending, now.UnixNano(), limit); err != nil {
+		return nil, nil, err
+	}
+
+	subscriptions := make([]model.WebhookSubscription, len(deliveries))
+	for i, delivery := range deliveries {
+		if _, err := tx.ExecContext(ctx, s.db.Rebind(`UPDATE webhook_delivery SET next_attempt_at = ? WHERE id = ?`),
+			now.Add(lease).UnixNano(), delivery.ID); err != nil {
+			return nil, nil, err
+		}
+
+		if err := tx.GetContext(ctx, &subscriptions[i], s.db.Rebind(`SELECT * FROM webhook_subscription WHERE id = ?`),
+			delivery.SubscriptionID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return deliveries, subscriptions, tx.Commit()
+}
+
+func (s *storage) RecordDeliveryAttempt(ctx context.Context, delivery model.WebhookDelivery, attempt model.WebhookDeliveryAttempt) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, s.db.Rebind(`INSERT INTO webhook_delivery_attempt (delivery_id, attempt, status_code, error,
+    duration_ms, created_at) VALUES(?, ?, ?, ?, ?, ?)`),
+		delivery.ID, attempt.Attempt, attempt.StatusCode, attempt.Error, attempt.DurationMs, attempt.CreatedAt); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, s.db.Rebind(`UPDATE webhook_delivery SET status = ?, attempts = ?, next_attempt_at = ?,
+    last_error = ?, delivered_at = ? WHERE id = ?`),
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.LastError, delivery.DeliveredAt, delivery.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *storage) GetDeliveries(ctx context.Context, username string, subscriptionID int64, limit int) ([]model.WebhookDelivery, error) {
+	deliveries := []model.WebhookDelivery{}
+	if err := s.db.SelectContext(ctx, &deliveries, s.db.Rebind(`SELECT d.* FROM webhook_delivery AS d INNER JOIN webhook_subscription AS ws
+    ON d.subscription_id = ws.id WHERE ws.username = ? AND ws.id = ? ORDER BY d.id DESC LIMIT ?`),
+		username, subscriptionID, limit); err != nil {
+		return nil, err
+	}
+
+	for i := range deliveries {
+		if err := s.db.SelectContext(ctx, &deliveries[i].Log, s.db.Rebind(`SELECT * FROM webhook_delivery_attempt WHERE delivery_id = ?
+    ORDER BY attempt`), deliveries[i].ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return deliveries, nil
+}
@@ -9,22 +9,27 @@ import (
 
 func NewStorage() *storage {
 	return &storage{
-		store: make(map[string]model.Transaction),
+		store: make(map[txKey]model.Transaction),
 	}
 }
 
-func (s *storage) GetTx(ctx context.Context, hash string) (model.Transaction, error) {
-	if tx, ok := s.store[hash]; ok {
+func (s *storage) GetTx(ctx context.Context, chainID uint64, hash string) (model.Transaction, error) {
+	if tx, ok := s.store[txKey{chainID: chainID, hash: hash}]; ok {
 		return tx, nil
 	}
 	return model.Transaction{}, fmt.Errorf("tx (%s) not found in storage", hash)
 }
 
 func (s *storage) StoreTx(ctx context.Context, transaction model.Transaction) error {
-	s.store[transaction.TransactionHash] = transaction
+	s.store[txKey{chainID: transaction.ChainID, hash: transaction.TransactionHash}] = transaction
 	return nil
 }
 
+type txKey struct {
+	chainID uint64
+	hash    string
+}
+
 type storage struct {
-	store map[string]model.Transaction
+	store map[txKey]model.Transaction
 }
@@ -0,0 +1,118 @@
+package txfetcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// replace marks a transaction the node no longer knows as replaced when another transaction
+// of the sender used its nonce. It reports whether the transaction was marked now.
+func (tf *txFetcher) replace(ctx context.Context, tx model.Transaction) (model.Transaction, bool, error) {
+	if tx.Nonce == nil {
+		return tx, false, nil
+	}
+
+	from := common.HexToAddress(tx.From)
+
+	nonce, err := tf.client.NonceAt(ctx, from, nil)
+	if err != nil {
+		return tx, false, err
+	}
+
+	if nonce <= *tx.Nonce {
+		return tx, false, nil
+	}
+
+	replacedBy, err := tf.storage.GetMinedTxHashByNonce(ctx, tf.chainID, tx.From, *tx.Nonce)
+	if err != nil {
+		return tx, false, err
+	}
+
+	if replacedBy == nil {
+		if replacedBy, err = tf.findReplacement(ctx, from, *tx.Nonce); err != nil {
+			// The transaction is replaced all the same, only the replacement is unknown
+			log.Println(fmt.Errorf("failed to find replacement of tx (%s): %s", tx.TransactionHash, err))
+		}
+	}
+
+	marked, err := tf.storage.MarkTxReplaced(ctx, tf.chainID, tx.TransactionHash, replacedBy)
+	if err != nil {
+		return tx, false, err
+	}
+
+	tx.TransactionStatus = model.Replaced
+	tx.ReplacedBy = replacedBy
+
+	if marked {
+		tf.publish(ctx, model.Event{Type: model.TxReplacedEvent, Transaction: &tx})
+	}
+
+	return tx, marked, nil
+}
+
+// findReplacement looks for the block in which the nonce of the sender was used among the recent
+// blocks, whose state is kept by non-archive nodes as well, and returns the transaction using it.
+func (tf *txFetcher) findReplacement(ctx context.Context, from common.Address, nonce uint64) (*string, error) {
+	head, err := tf.client.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	low := uint64(0)
+	if head > replacementSearchDepth {
+		low = head - replacementSearchDepth
+	}
+	high := head
+
+	lowNonce, err := tf.client.NonceAt(ctx, from, new(big.Int).SetUint64(low))
+	if err != nil {
+		return nil, err
+	}
+
+	if lowNonce > nonce {
+		return nil, fmt.Errorf("nonce (%d) was used before block (%d)", nonce, low)
+	}
+
+	// The nonce is unused at low and used at high
+	for high-low > 1 {
+		mid := low + (high-low)/2
+
+		midNonce, err := tf.client.NonceAt(ctx, from, new(big.Int).SetUint64(mid))
+		if err != nil {
+			return nil, err
+		}
+
+		if midNonce > nonce {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+
+	block, err := tf.client.BlockByNumber(ctx, new(big.Int).SetUint64(high))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, blockTx := range block.Transactions() {
+		if blockTx.Nonce() != nonce {
+			continue
+		}
+
+		sender, err := types.Sender(types.LatestSignerForChainID(blockTx.ChainId()), blockTx)
+		if err == nil && sender == from {
+			hash := blockTx.Hash().Hex()
+			return &hash, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no tx with nonce (%d) in block (%d)", nonce, high)
+}
+
+const replacementSearchDepth = 128
@@ -3,33 +3,53 @@ package txfetcher
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
+	"math/big"
 	"sync"
 	"time"
 
 	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
-func NewTxFetcher(storage storage, client client) *txFetcher {
+// When verification is enabled, only transactions that passed every enabled check are cached.
+// Mined transactions are watched for reorgs until they have the given number of confirmations.
+// The contracts deployed by stored transactions are handed to the inspector.
+func NewTxFetcher(chainID uint64, storage storage, client client, verifier verifier, verification Verification,
+	confirmations uint64, publisher publisher, inspector inspector) *txFetcher {
 	return &txFetcher{
-		storage: storage,
-		client:  client,
+		chainID:       chainID,
+		storage:       storage,
+		client:        client,
+		verifier:      verifier,
+		verification:  verification,
+		confirmations: confirmations,
+		publisher:     publisher,
+		inspector:     inspector,
+		misses:        make(map[string]int),
 	}
 }
 
-func (tf *txFetcher) FetchTx(ctx context.Context, token *string, txHashes []string) ([]model.Transaction, error) {
+// SetMempool lets lookups the node cannot answer fall back to the mempool index and records when
+// transactions were first seen. It must be called before the fetcher is used.
+func (tf *txFetcher) SetMempool(mempool mempool) {
+	tf.mempool = mempool
+}
+
+func (tf *txFetcher) FetchTx(ctx context.Context, owner *model.TxOwner, txHashes []string) ([]model.Transaction, error) {
 	count := len(txHashes)
 
 	var wg sync.WaitGroup
 	wg.Add(count)
 
-	results := make(chan model.Transaction, count)
+	results := make(chan fetchResult, count)
 
 	for i := 0; i < count; i++ {
-		go tf.fetchTx(ctx, token, txHashes[i], results, &wg)
+		go tf.fetchTx(ctx, owner, txHashes[i], results, &wg)
 	}
 
 	go func() {
@@ -38,32 +58,108 @@ func (tf *txFetcher) FetchTx(ctx context.Context, token *string, txHashes []stri
 	}()
 
 	transactions := []model.Transaction{}
+	failed := []string{}
+	var fetchErr error
 	for res := range results {
-		transactions = append(transactions, res)
+		if res.err != nil {
+			failed = append(failed, res.hash)
+			// Failures of the node or the database take precedence over unknown hashes
+			if fetchErr == nil || errors.Is(fetchErr, ethereum.NotFound) {
+				fetchErr = res.err
+			}
+			continue
+		}
+		transactions = append(transactions, res.tx)
 	}
 
-	if len(transactions) != count {
-		return nil, fmt.Errorf("failed to fetch transactions: %v", txHashes)
+	if fetchErr != nil {
+		return nil, fmt.Errorf("failed to fetch transactions: %v: %w", failed, fetchErr)
 	}
 
 	return transactions, nil
 }
 
-func (tf *txFetcher) fetchTx(ctx context.Context, token *string, hash string, results chan model.Transaction, wg *sync.WaitGroup) {
+// fetchTx sends an error wrapping ethereum.NotFound when neither the node nor the cache know the hash.
+func (tf *txFetcher) fetchTx(ctx context.Context, owner *model.TxOwner, hash string, results chan fetchResult, wg *sync.WaitGroup) {
 	defer wg.Done()
 
-	tx, err := tf.storage.GetTx(ctx, hash)
+	tx, isTrusted, err := tf.fetchOne(ctx, hash)
+	if err != nil {
+		if pendingTx, ok := tf.mempoolTx(hash); ok {
+			tx, isTrusted, err = pendingTx, true, nil
+		}
+	}
+	if errors.Is(err, ethereum.NotFound) {
+		// The node forgets replaced and dropped transactions, their stored state is returned
+		if tx, err = tf.fetchStored(ctx, hash); err != nil {
+			log.Println(err)
+			results <- fetchResult{hash: hash, err: err}
+			return
+		}
+		results <- fetchResult{hash: hash, tx: tx}
+		return
+	}
 	if err != nil {
-		// The storage layer returns error if not found
 		log.Println(err)
+		results <- fetchResult{hash: hash, err: err}
+		return
 	}
 
+	// Pending transactions are stored too, so they are followed and their replacement is detected
+	if tx.TransactionStatus == model.Pending || isTrusted {
+		go func() {
+			ctxWithTimeout, cancelFunc := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancelFunc()
+			if err := tf.store(ctxWithTimeout, tx, owner); err != nil {
+				log.Println(fmt.Errorf("failed to store tx (%s): %s", tx.TransactionHash, err))
+				return
+			}
+
+			inspectCtx, cancelInspect := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancelInspect()
+			tf.recordCreation(inspectCtx, tx)
+		}()
+	}
+
+	results <- fetchResult{hash: hash, tx: tx}
+}
+
+func (tf *txFetcher) fetchStored(ctx context.Context, hash string) (model.Transaction, error) {
+	transactions, err := tf.storage.GetTxs(ctx, tf.chainID, []string{hash})
+	if err != nil {
+		return model.Transaction{}, err
+	}
+	if len(transactions) == 0 {
+		return model.Transaction{}, fmt.Errorf("tx (%s) %w", hash, ethereum.NotFound)
+	}
+
+	tx := transactions[0]
+	if tx.TransactionStatus != model.Pending && tx.TransactionStatus != model.Dropped {
+		return tx, nil
+	}
+
+	replacedTx, _, err := tf.replace(ctx, tx)
+	if err != nil {
+		log.Println(fmt.Errorf("failed to check replacement of tx (%s): %s", hash, err))
+		return tx, nil
+	}
+
+	return replacedTx, nil
+}
+
+// fetchOne reports whether the transaction passed every enabled verification. It returns
+// ethereum.NotFound when the node does not know the transaction.
+func (tf *txFetcher) fetchOne(ctx context.Context, hash string) (model.Transaction, bool, error) {
 	txHash := common.HexToHash(hash)
 
+	var firstSeenAt *int64
+	if tf.mempool != nil {
+		firstSeenAt = tf.mempool.FirstSeen(txHash)
+	}
+
 	rawTx, isPending, err := tf.client.TransactionByHash(ctx, txHash)
 	if err != nil {
-		log.Println(err)
-		return
+		return model.Transaction{}, false, err
 	}
 
 	var receipt *types.Receipt
@@ -71,39 +167,307 @@ func (tf *txFetcher) fetchTx(ctx context.Context, token *string, hash string, re
 	if !isPending {
 		receipt, err = tf.client.TransactionReceipt(ctx, txHash)
 		if err != nil {
-			log.Println(err)
+			return model.Transaction{}, false, err
+		}
+	}
+
+	verified, receiptVerified := false, false
+	if !isPending && tf.verification.Inclusion {
+		verifiedTx, err := tf.verifier.VerifyInclusion(ctx, receipt.BlockHash, txHash)
+		if err != nil {
+			log.Println(fmt.Errorf("failed to verify inclusion of tx (%s): %s", hash, err))
+		} else {
+			rawTx = verifiedTx
+			verified = true
+		}
+	}
+
+	if !isPending && tf.verification.Receipts {
+		verifiedReceipt, err := tf.verifier.VerifyReceipt(ctx, receipt.BlockHash, txHash)
+		if err != nil {
+			log.Println(fmt.Errorf("failed to verify receipt of tx (%s): %s", hash, err))
+		} else {
+			receipt = verifiedReceipt
+			receiptVerified = true
+		}
+	}
+
+	tx, err := parseRawTx(tf.chainID, rawTx, receipt, isPending)
+	if err != nil {
+		return model.Transaction{}, false, err
+	}
+	tx.Verified = verified
+	tx.ReceiptVerified = receiptVerified
+	tx.FirstSeenAt = firstSeenAt
+
+	isTrusted := (!tf.verification.Inclusion || verified) && (!tf.verification.Receipts || receiptVerified)
+
+	return tx, isTrusted, nil
+}
+
+// mempoolTx answers from the mempool index when the node could not. The index does not learn about
+// every inclusion, so it never overrides the node and refreshPending does not use it.
+func (tf *txFetcher) mempoolTx(hash string) (model.Transaction, bool) {
+	if tf.mempool == nil {
+		return model.Transaction{}, false
+	}
+
+	rawTx, seenAt, ok := tf.mempool.Pending(common.HexToHash(hash))
+	if !ok {
+		return model.Transaction{}, false
+	}
+
+	tx, err := parseRawTx(tf.chainID, rawTx, nil, true)
+	if err != nil {
+		return model.Transaction{}, false
+	}
+	tx.FirstSeenAt = &seenAt
+
+	return tx, true
+}
+
+func (tf *txFetcher) ListCachedTxs(ctx context.Context, query model.TxQuery) ([]model.Transaction, error) {
+	query.Filter.ChainID = tf.chainID
+	return tf.storage.ListTxs(ctx, query)
+}
+
+// Track stores a transaction submitted through the service so it is followed until mined.
+func (tf *txFetcher) Track(ctx context.Context, rawTx *types.Transaction, owner *model.TxOwner) error {
+	tx, err := parseRawTx(tf.chainID, rawTx, nil, true)
+	if err != nil {
+		return err
+	}
+	_, err = tf.storage.StoreTx(ctx, tx, owner)
+	return err
+}
+
+// Follow makes the owner receive the status events of the transactions. Pending ones are stored
+// so they are refreshed until mined.
+func (tf *txFetcher) Follow(ctx context.Context, owner *model.TxOwner, txHashes []string) ([]model.Transaction, error) {
+	transactions, err := tf.FetchTx(ctx, owner, txHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tx := range transactions {
+		if tx.TransactionStatus != model.Pending {
+			continue
+		}
+		if _, err := tf.storage.StoreTx(ctx, tx, owner); err != nil {
+			return nil, err
+		}
+	}
+
+	return transactions, nil
+}
+
+func (tf *txFetcher) FollowPending(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
 			return
+		case <-ticker.C:
+			tf.refreshPending(ctx)
+			tf.refreshUnfinalized(ctx)
 		}
 	}
+}
 
-	tx, err = parseRawTx(rawTx, receipt, isPending)
+func (tf *txFetcher) refreshPending(ctx context.Context) {
+	pendingTxs, err := tf.storage.GetPendingTxs(ctx, tf.chainID)
 	if err != nil {
-		log.Println(err)
+		log.Println(fmt.Errorf("failed to load pending txs of chain (%d): %s", tf.chainID, err))
 		return
 	}
 
-	if !isPending {
-		go func() {
-			ctxWithTimeout, cancelFunc := context.WithTimeout(context.Background(), 2*time.Second)
-			defer cancelFunc()
-			if err := tf.storage.StoreTx(ctxWithTimeout, tx, token); err != nil {
-				log.Println(fmt.Errorf("failed to store tx (%s): %s", tx.TransactionHash, err))
+	for _, pendingTx := range pendingTxs {
+		hash := pendingTx.TransactionHash
+
+		tx, isTrusted, err := tf.fetchOne(ctx, hash)
+		if errors.Is(err, ethereum.NotFound) {
+			_, replaced, err := tf.replace(ctx, pendingTx)
+			if err != nil {
+				log.Println(fmt.Errorf("failed to check replacement of tx (%s): %s", hash, err))
 			}
-		}()
+			if replaced {
+				delete(tf.misses, hash)
+				continue
+			}
+			tf.missed(ctx, pendingTx)
+			continue
+		}
+		if err != nil {
+			log.Println(fmt.Errorf("failed to refresh pending tx (%s): %s", hash, err))
+			continue
+		}
+
+		delete(tf.misses, hash)
+
+		if tx.TransactionStatus == model.Pending || !isTrusted {
+			continue
+		}
+
+		if err := tf.store(ctx, tx, nil); err != nil {
+			log.Println(fmt.Errorf("failed to store tx (%s): %s", hash, err))
+			continue
+		}
+		tf.recordCreation(ctx, tx)
+	}
+}
+
+// store publishes the outcome of followed transactions, whichever lookup finds them mined first.
+func (tf *txFetcher) store(ctx context.Context, tx model.Transaction, owner *model.TxOwner) error {
+	previousStatus, err := tf.storage.StoreTx(ctx, tx, owner)
+	if err != nil {
+		return err
+	}
+
+	if previousStatus == nil || (*previousStatus != model.Pending && *previousStatus != model.Dropped) {
+		return nil
 	}
 
-	results <- tx
+	switch tx.TransactionStatus {
+	case model.Successful:
+		tf.publish(ctx, model.Event{Type: model.TxConfirmedEvent, Transaction: &tx})
+	case model.Failed:
+		tf.publish(ctx, model.Event{Type: model.TxFailedEvent, Transaction: &tx})
+	}
+
+	return nil
 }
 
-func (tf *txFetcher) FetchAllCachedTx(ctx context.Context) ([]model.Transaction, error) {
-	return tf.storage.GetAllTxs(ctx)
+// refreshUnfinalized checks that mined transactions are still in the canonical chain. Those
+// that left it go back to pending and are picked up by refreshPending again.
+func (tf *txFetcher) refreshUnfinalized(ctx context.Context) {
+	unfinalizedTxs, err := tf.storage.GetUnfinalizedTxs(ctx, tf.chainID)
+	if err != nil {
+		log.Println(fmt.Errorf("failed to load unfinalized txs of chain (%d): %s", tf.chainID, err))
+		return
+	}
+
+	if len(unfinalizedTxs) == 0 {
+		return
+	}
+
+	head, err := tf.client.BlockNumber(ctx)
+	if err != nil {
+		log.Println(fmt.Errorf("failed to get head of chain (%d): %s", tf.chainID, err))
+		return
+	}
+
+	for _, tx := range unfinalizedTxs {
+		receipt, err := tf.client.TransactionReceipt(ctx, common.HexToHash(tx.TransactionHash))
+		if err != nil && !errors.Is(err, ethereum.NotFound) {
+			log.Println(fmt.Errorf("failed to refresh mined tx (%s): %s", tx.TransactionHash, err))
+			continue
+		}
+
+		if err != nil || tx.BlockHash == nil || receipt.BlockHash.Hex() != *tx.BlockHash {
+			reorged, err := tf.isReorged(ctx, tx)
+			if err != nil {
+				log.Println(fmt.Errorf("failed to check mined tx (%s) for a reorg: %s", tx.TransactionHash, err))
+				continue
+			}
+			if !reorged {
+				continue
+			}
+
+			if err := tf.storage.ReorgTx(ctx, tf.chainID, tx.TransactionHash); err != nil {
+				log.Println(fmt.Errorf("failed to mark tx (%s) as reorged: %s", tx.TransactionHash, err))
+				continue
+			}
+
+			reorgedTx := tx
+			reorgedTx.TransactionStatus = model.Pending
+			reorgedTx.BlockHash, reorgedTx.BlockNumber = nil, nil
+			tf.publish(ctx, model.Event{Type: model.TxReorgedEvent, Transaction: &reorgedTx})
+			continue
+		}
+
+		if head < *tx.BlockNumber || head-*tx.BlockNumber+1 < tf.confirmations {
+			continue
+		}
+
+		if err := tf.storage.FinalizeTx(ctx, tf.chainID, tx.TransactionHash); err != nil {
+			log.Println(fmt.Errorf("failed to finalize tx (%s): %s", tx.TransactionHash, err))
+			continue
+		}
+
+		confirmations := head - *tx.BlockNumber + 1
+		tx.Finalized = true
+		tf.publish(ctx, model.Event{Type: model.TxFinalizedEvent, Transaction: &tx, Confirmations: &confirmations})
+	}
 }
 
-func (tf *txFetcher) FetchAllCachedTxByToken(ctx context.Context, token string) ([]model.Transaction, error) {
-	return tf.storage.GetTxsByToken(ctx, token)
+// isReorged compares the canonical block at the height of the tx with the stored one. A missing
+// receipt alone is not enough, nodes that lag behind or prune receipts do not return it either.
+func (tf *txFetcher) isReorged(ctx context.Context, tx model.Transaction) (bool, error) {
+	if tx.BlockHash == nil || tx.BlockNumber == nil {
+		return true, nil
+	}
+
+	header, err := tf.client.HeaderByNumber(ctx, new(big.Int).SetUint64(*tx.BlockNumber))
+	if errors.Is(err, ethereum.NotFound) {
+		// The canonical chain is shorter than the stored block
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return header.Hash().Hex() != *tx.BlockHash, nil
+}
+
+func (tf *txFetcher) recordCreation(ctx context.Context, tx model.Transaction) {
+	if tx.ContractAddress == nil || tx.TransactionStatus != model.Successful {
+		return
+	}
+
+	if err := tf.inspector.RecordCreation(ctx, common.HexToAddress(*tx.ContractAddress), tx.TransactionHash); err != nil {
+		log.Println(fmt.Errorf("failed to inspect contract created by tx (%s): %s", tx.TransactionHash, err))
+	}
+}
+
+// A pending transaction the node stopped knowing about for several polls in a row
+// was evicted from the mempool and is marked as dropped.
+func (tf *txFetcher) missed(ctx context.Context, tx model.Transaction) {
+	tf.misses[tx.TransactionHash]++
+	if tf.misses[tx.TransactionHash] < maxMisses {
+		return
+	}
+
+	delete(tf.misses, tx.TransactionHash)
+
+	if err := tf.storage.UpdateTxStatus(ctx, tf.chainID, tx.TransactionHash, model.Dropped); err != nil {
+		log.Println(fmt.Errorf("failed to mark tx (%s) as dropped: %s", tx.TransactionHash, err))
+		return
+	}
+
+	tx.TransactionStatus = model.Dropped
+	tf.publish(ctx, model.Event{Type: model.TxDroppedEvent, Transaction: &tx})
+}
+
+// publish sends a copy of the event to every user that owns its transaction.
+func (tf *txFetcher) publish(ctx context.Context, event model.Event) {
+	usernames, err := tf.storage.GetTxOwners(ctx, tf.chainID, event.Transaction.TransactionHash)
+	if err != nil {
+		log.Println(fmt.Errorf("failed to load owners of tx (%s): %s", event.Transaction.TransactionHash, err))
+		return
+	}
+
+	event.ChainID = tf.chainID
+	event.CreatedAt = time.Now().UnixNano()
+
+	for _, username := range usernames {
+		event.Username = username
+		tf.publisher.Publish(event)
+	}
 }
 
-func parseRawTx(tx *types.Transaction, receipt *types.Receipt, isPending bool) (model.Transaction, error) {
+func parseRawTx(chainID uint64, tx *types.Transaction, receipt *types.Receipt, isPending bool) (model.Transaction, error) {
 	var status model.TxStatus
 
 	if isPending {
@@ -112,16 +476,20 @@ func parseRawTx(tx *types.Transaction, receipt *types.Receipt, isPending bool) (
 		status = model.TxStatus(receipt.Status)
 	}
 
-	txMsg := getTransactionMessage(tx)
+	from := getTransactionSender(tx)
 
 	parsedTx := model.Transaction{
+		ChainID:           chainID,
 		TransactionHash:   tx.Hash().Hex(),
 		TransactionStatus: status,
-		From:              txMsg.From().Hex(),
+		From:              from.Hex(),
 		Input:             hex.EncodeToString(tx.Data()),
 		Value:             tx.Value().String(),
 	}
 
+	nonce := tx.Nonce()
+	parsedTx.Nonce = &nonce
+
 	if tx.To() != nil {
 		to := tx.To().Hex()
 		parsedTx.To = &to
@@ -141,6 +509,9 @@ func parseRawTx(tx *types.Transaction, receipt *types.Receipt, isPending bool) (
 		blockNumber := receipt.BlockNumber.Uint64()
 		parsedTx.BlockNumber = &blockNumber
 
+		transactionIndex := receipt.TransactionIndex
+		parsedTx.TransactionIndex = &transactionIndex
+
 		logsCount := len(receipt.Logs)
 		parsedTx.LogsCount = &logsCount
 	}
@@ -148,27 +519,79 @@ func parseRawTx(tx *types.Transaction, receipt *types.Receipt, isPending bool) (
 	return parsedTx, nil
 }
 
-func getTransactionMessage(tx *types.Transaction) types.Message {
-	msg, err := tx.AsMessage(types.LatestSignerForChainID(tx.ChainId()), nil)
+func getTransactionSender(tx *types.Transaction) common.Address {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
 	if err != nil {
 		log.Println(err)
 	}
-	return msg
+	return from
 }
 
+const maxMisses = 4
+
 type storage interface {
-	GetTx(ctx context.Context, hash string) (model.Transaction, error)
-	StoreTx(ctx context.Context, transaction model.Transaction, token *string) error
-	GetAllTxs(ctx context.Context) ([]model.Transaction, error)
-	GetTxsByToken(ctx context.Context, token string) ([]model.Transaction, error)
+	GetTxs(ctx context.Context, chainID uint64, hashes []string) ([]model.Transaction, error)
+	StoreTx(ctx context.Context, transaction model.Transaction, owner *model.TxOwner) (*model.TxStatus, error)
+	ListTxs(ctx context.Context, query model.TxQuery) ([]model.Transaction, error)
+	GetPendingTxs(ctx context.Context, chainID uint64) ([]model.Transaction, error)
+	GetTxOwners(ctx context.Context, chainID uint64, hash string) ([]string, error)
+	UpdateTxStatus(ctx context.Context, chainID uint64, hash string, status model.TxStatus) error
+	GetUnfinalizedTxs(ctx context.Context, chainID uint64) ([]model.Transaction, error)
+	FinalizeTx(ctx context.Context, chainID uint64, hash string) error
+	ReorgTx(ctx context.Context, chainID uint64, hash string) error
+	MarkTxReplaced(ctx context.Context, chainID uint64, hash string, replacedBy *string) (bool, error)
+	GetMinedTxHashByNonce(ctx context.Context, chainID uint64, from string, nonce uint64) (*string, error)
 }
 
 type client interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
 	TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error)
 	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
 }
 
+type verifier interface {
+	VerifyInclusion(ctx context.Context, blockHash, txHash common.Hash) (*types.Transaction, error)
+	VerifyReceipt(ctx context.Context, blockHash, txHash common.Hash) (*types.Receipt, error)
+}
+
+// Verification selects the checks run against the block before a transaction is trusted.
+type Verification struct {
+	Inclusion bool
+	Receipts  bool
+}
+
+type mempool interface {
+	Pending(hash common.Hash) (*types.Transaction, int64, bool)
+	FirstSeen(hash common.Hash) *int64
+}
+
+type inspector interface {
+	RecordCreation(ctx context.Context, address common.Address, txHash string) error
+}
+
+type publisher interface {
+	Publish(event model.Event)
+}
+
+type fetchResult struct {
+	hash string
+	tx   model.Transaction
+	err  error
+}
+
 type txFetcher struct {
-	storage storage
-	client  client
+	chainID       uint64
+	storage       storage
+	client        client
+	verifier      verifier
+	verification  Verification
+	confirmations uint64
+	publisher     publisher
+	mempool       mempool
+	inspector     inspector
+	// misses is only touched by FollowPending
+	misses map[string]int
 }
@@ -0,0 +1,166 @@
+package txsender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func NewTxSender(chainID uint64, client client, tracker tracker, feeCap *big.Int) *txSender {
+	return &txSender{
+		chainID: chainID,
+		client:  client,
+		tracker: tracker,
+		feeCap:  feeCap,
+	}
+}
+
+// SetNonceManager sends the transactions of managed addresses through the nonce manager, so they
+// cannot take a nonce that is reserved or used by another send.
+func (ts *txSender) SetNonceManager(nonceManager nonceManager) {
+	ts.nonceManager = nonceManager
+}
+
+func (ts *txSender) SendRawTx(ctx context.Context, owner *model.TxOwner, rawTx string) (string, error) {
+	encoded, err := hexutil.Decode(rawTx)
+	if err != nil {
+		return "", fmt.Errorf("invalid raw tx: %s", err)
+	}
+
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(encoded); err != nil {
+		return "", fmt.Errorf("failed to decode raw tx: %s", err)
+	}
+
+	sender, err := ts.validate(ctx, tx)
+	if err != nil {
+		return "", err
+	}
+
+	if ts.nonceManager != nil && ts.nonceManager.Manages(sender) {
+		err = ts.nonceManager.SendSigned(ctx, sender, owner.Username, tx)
+	} else {
+		err = ts.client.SendTransaction(ctx, tx)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := ts.tracker.Track(ctx, tx, owner); err != nil {
+		return "", fmt.Errorf("tx (%s) was sent but failed to be tracked: %s", tx.Hash().Hex(), err)
+	}
+
+	return tx.Hash().Hex(), nil
+}
+
+func (ts *txSender) validate(ctx context.Context, tx *types.Transaction) (common.Address, error) {
+	if tx.ChainId().Uint64() != ts.chainID {
+		return common.Address{}, fmt.Errorf("tx chain id (%d) does not match chain (%d)", tx.ChainId().Uint64(), ts.chainID)
+	}
+
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid signature: %s", err)
+	}
+
+	minedNonce, err := ts.client.NonceAt(ctx, sender, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	// Nonces of transactions waiting in the mempool are accepted, the node decides whether
+	// the transaction pays enough to replace them
+	if tx.Nonce() < minedNonce {
+		return common.Address{}, fmt.Errorf("nonce too low: tx nonce %d, mined nonce %d", tx.Nonce(), minedNonce)
+	}
+
+	pendingNonce, err := ts.client.PendingNonceAt(ctx, sender)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	if tx.Nonce() > pendingNonce+maxNonceGap {
+		return common.Address{}, fmt.Errorf("nonce too high: tx nonce %d, pending nonce %d", tx.Nonce(), pendingNonce)
+	}
+
+	if err := ts.validateFees(ctx, tx); err != nil {
+		return common.Address{}, err
+	}
+
+	// The pending balance already pays for the transaction a replacement takes the place of
+	var balance *big.Int
+	if tx.Nonce() < pendingNonce {
+		balance, err = ts.client.BalanceAt(ctx, sender, nil)
+	} else {
+		balance, err = ts.client.PendingBalanceAt(ctx, sender)
+	}
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	if balance.Cmp(tx.Cost()) < 0 {
+		return common.Address{}, fmt.Errorf("insufficient funds: balance %s, tx cost %s", balance, tx.Cost())
+	}
+
+	return sender, nil
+}
+
+func (ts *txSender) validateFees(ctx context.Context, tx *types.Transaction) error {
+	if tx.GasFeeCap().Sign() == 0 {
+		return errors.New("gas price is zero")
+	}
+
+	if tx.GasFeeCap().Cmp(tx.GasTipCap()) < 0 {
+		return fmt.Errorf("max priority fee (%s) is higher than max fee (%s)", tx.GasTipCap(), tx.GasFeeCap())
+	}
+
+	maxFee := new(big.Int).Mul(tx.GasFeeCap(), new(big.Int).SetUint64(tx.Gas()))
+	if maxFee.Cmp(ts.feeCap) > 0 {
+		return fmt.Errorf("tx fee (%s wei) exceeds the configured cap (%s wei)", maxFee, ts.feeCap)
+	}
+
+	header, err := ts.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if header.BaseFee != nil && tx.GasFeeCap().Cmp(header.BaseFee) < 0 {
+		return fmt.Errorf("max fee (%s) is below the current base fee (%s)", tx.GasFeeCap(), header.BaseFee)
+	}
+
+	return nil
+}
+
+const maxNonceGap = 16
+
+type client interface {
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	PendingBalanceAt(ctx context.Context, account common.Address) (*big.Int, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+}
+
+type nonceManager interface {
+	Manages(address common.Address) bool
+	SendSigned(ctx context.Context, from common.Address, username string, tx *types.Transaction) error
+}
+
+type tracker interface {
+	Track(ctx context.Context, tx *types.Transaction, owner *model.TxOwner) error
+}
+
+type txSender struct {
+	chainID      uint64
+	client       client
+	tracker      tracker
+	feeCap       *big.Int
+	nonceManager nonceManager
+}
@@ -0,0 +1,115 @@
+package txsender
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestValidateNonce(t *testing.T) {
+	tests := []struct {
+		name    string
+		nonce   uint64
+		wantErr bool
+	}{
+		{"below the mined nonce", 4, true},
+		{"replacement of a pending transaction", 5, false},
+		{"last pending nonce", 7, false},
+		{"next nonce", 8, false},
+		{"largest allowed gap", 8 + maxNonceGap, false},
+		{"gap too large", 9 + maxNonceGap, true},
+	}
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(1)), &types.DynamicFeeTx{
+				ChainID:   big.NewInt(1),
+				Nonce:     test.nonce,
+				GasTipCap: big.NewInt(params.GWei),
+				GasFeeCap: big.NewInt(10 * params.GWei),
+				Gas:       21000,
+				To:        &common.Address{},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ts := NewTxSender(1, fakeClient{minedNonce: 5, pendingNonce: 8}, nil, big.NewInt(params.Ether))
+			if _, err := ts.validate(context.Background(), tx); (err != nil) != test.wantErr {
+				t.Fatalf("validate = %v, want error %t", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateReplacementBalance(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(1)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     5,
+		GasTipCap: big.NewInt(params.GWei),
+		GasFeeCap: big.NewInt(10 * params.GWei),
+		Gas:       21000,
+		To:        &common.Address{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The pending balance is spent by the transaction being replaced
+	client := fakeClient{minedNonce: 5, pendingNonce: 6, balance: big.NewInt(params.Ether), pendingBalance: big.NewInt(0)}
+	if _, err := NewTxSender(1, client, nil, big.NewInt(params.Ether)).validate(context.Background(), tx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type fakeClient struct {
+	minedNonce     uint64
+	pendingNonce   uint64
+	balance        *big.Int
+	pendingBalance *big.Int
+}
+
+func (c fakeClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return c.minedNonce, nil
+}
+
+func (c fakeClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return c.pendingNonce, nil
+}
+
+func (c fakeClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	if c.balance == nil {
+		return big.NewInt(params.Ether), nil
+	}
+	return c.balance, nil
+}
+
+func (c fakeClient) PendingBalanceAt(ctx context.Context, account common.Address) (*big.Int, error) {
+	if c.pendingBalance == nil {
+		return big.NewInt(params.Ether), nil
+	}
+	return c.pendingBalance, nil
+}
+
+func (c fakeClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(1), BaseFee: big.NewInt(params.GWei)}, nil
+}
+
+func (c fakeClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	return nil
+}
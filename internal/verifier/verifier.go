@@ -0,0 +1,152 @@
+package verifier
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+func NewVerifier(client client) *verifier {
+	return &verifier{
+		client:   client,
+		receipts: lru.NewCache[common.Hash, types.Receipts](receiptsCacheSize),
+	}
+}
+
+// VerifyInclusion rebuilds the transactions trie of the block from the raw transactions
+// and checks it against the header's transactionsRoot. The returned transaction is the
+// one from the verified block whose hash was recomputed locally from its RLP encoding.
+func (v *verifier) VerifyInclusion(ctx context.Context, blockHash, txHash common.Hash) (*types.Transaction, error) {
+	block, err := v.block(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := block.Transactions()
+
+	txRoot := types.DeriveSha(transactions, trie.NewStackTrie(nil))
+	if txRoot != block.TxHash() {
+		return nil, fmt.Errorf("transactions root mismatch in block (%s): header (%s), computed (%s)",
+			blockHash.Hex(), block.TxHash().Hex(), txRoot.Hex())
+	}
+
+	for _, tx := range transactions {
+		if tx.Hash() == txHash {
+			return tx, nil
+		}
+	}
+
+	return nil, fmt.Errorf("tx (%s) is not included in block (%s)", txHash.Hex(), blockHash.Hex())
+}
+
+// VerifyReceipt fetches the receipts of the block, rebuilds the receipts trie and checks it
+// against the header's receiptsRoot. The returned receipt is the verified one of the requested
+// transaction. Verified receipts are cached by block hash.
+func (v *verifier) VerifyReceipt(ctx context.Context, blockHash, txHash common.Hash) (*types.Receipt, error) {
+	block, err := v.block(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	receipts, ok := v.receipts.Get(blockHash)
+	if !ok {
+		if receipts, err = v.blockReceipts(ctx, block); err != nil {
+			return nil, err
+		}
+
+		receiptRoot := types.DeriveSha(receipts, trie.NewStackTrie(nil))
+		if receiptRoot != block.ReceiptHash() {
+			return nil, fmt.Errorf("receipts root mismatch in block (%s): header (%s), computed (%s)",
+				blockHash.Hex(), block.ReceiptHash().Hex(), receiptRoot.Hex())
+		}
+
+		v.receipts.Add(blockHash, receipts)
+	}
+
+	for i, tx := range block.Transactions() {
+		if tx.Hash() == txHash {
+			return receipts[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("tx (%s) is not included in block (%s)", txHash.Hex(), blockHash.Hex())
+}
+
+// blockReceipts uses eth_getBlockReceipts and falls back to one request per transaction on
+// nodes that do not support it.
+func (v *verifier) blockReceipts(ctx context.Context, block *types.Block) (types.Receipts, error) {
+	transactions := block.Transactions()
+
+	receipts, err := v.client.BlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(block.Hash(), true))
+	if err == nil && len(receipts) == len(transactions) {
+		return receipts, nil
+	}
+
+	receipts = make(types.Receipts, len(transactions))
+	errs := make(chan error, len(transactions))
+	semaphore := make(chan struct{}, maxConcurrentReceipts)
+
+	var wg sync.WaitGroup
+	wg.Add(len(transactions))
+
+	for i, tx := range transactions {
+		go func(i int, hash common.Hash) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			receipt, err := v.client.TransactionReceipt(ctx, hash)
+			if err != nil {
+				errs <- fmt.Errorf("failed to get receipt of tx (%s): %s", hash.Hex(), err)
+				return
+			}
+			receipts[i] = receipt
+		}(i, tx.Hash())
+	}
+
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+
+	return receipts, nil
+}
+
+// The header is hashed locally, so a node cannot hand out a header of a different block.
+func (v *verifier) block(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
+	block, err := v.client.BlockByHash(ctx, blockHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block (%s): %s", blockHash.Hex(), err)
+	}
+
+	if block.Hash() != blockHash {
+		return nil, fmt.Errorf("header of block (%s) hashes to (%s)", blockHash.Hex(), block.Hash().Hex())
+	}
+
+	return block, nil
+}
+
+const (
+	maxConcurrentReceipts = 16
+	receiptsCacheSize     = 128
+)
+
+type client interface {
+	BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error)
+	BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+type verifier struct {
+	client   client
+	receipts *lru.Cache[common.Hash, types.Receipts]
+}
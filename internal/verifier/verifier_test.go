@@ -0,0 +1,208 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+func TestVerifyInclusion(t *testing.T) {
+	block, _ := newTestBlock(t)
+	transactions := block.Transactions()
+	other := newTestTx(t, 99)
+
+	tests := []struct {
+		name    string
+		served  *types.Block
+		txHash  common.Hash
+		wantErr bool
+	}{
+		{"included tx", block, transactions[1].Hash(), false},
+		{"tx of another block", block, other.Hash(), true},
+		{"tampered transactions", block.WithBody(types.Body{Transactions: append(types.Transactions{other}, transactions[1:]...)}), transactions[1].Hash(), true},
+		{"missing transaction", block.WithBody(types.Body{Transactions: transactions[1:]}), transactions[1].Hash(), true},
+		{"another header", types.NewBlockWithHeader(&types.Header{Number: big.NewInt(8)}), transactions[1].Hash(), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewVerifier(&fakeClient{block: test.served})
+
+			tx, err := v.VerifyInclusion(context.Background(), block.Hash(), test.txHash)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("inclusion verified")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tx.Hash() != test.txHash {
+				t.Fatalf("verified tx (%s), want (%s)", tx.Hash().Hex(), test.txHash.Hex())
+			}
+		})
+	}
+}
+
+func TestVerifyReceipt(t *testing.T) {
+	block, receipts := newTestBlock(t)
+	txHash := block.Transactions()[2].Hash()
+
+	tampered := func(change func(receipt *types.Receipt)) types.Receipts {
+		copied := make(types.Receipts, len(receipts))
+		for i, receipt := range receipts {
+			c := *receipt
+			copied[i] = &c
+		}
+		change(copied[1])
+		return copied
+	}
+
+	tests := []struct {
+		name     string
+		receipts types.Receipts
+		wantErr  bool
+	}{
+		{"valid receipts", receipts, false},
+		{"tampered status", tampered(func(receipt *types.Receipt) { receipt.Status = types.ReceiptStatusFailed }), true},
+		{"tampered gas", tampered(func(receipt *types.Receipt) { receipt.CumulativeGasUsed++ }), true},
+		{"tampered logs", tampered(func(receipt *types.Receipt) {
+			receipt.Logs = []*types.Log{{Address: common.HexToAddress("0x01")}}
+		}), true},
+		{"reordered receipts", types.Receipts{receipts[1], receipts[0], receipts[2]}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewVerifier(&fakeClient{block: block, blockReceipts: test.receipts})
+
+			receipt, err := v.VerifyReceipt(context.Background(), block.Hash(), txHash)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("tampered receipts verified")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if receipt.TxHash != txHash {
+				t.Fatalf("verified receipt of tx (%s), want (%s)", receipt.TxHash.Hex(), txHash.Hex())
+			}
+		})
+	}
+}
+
+func TestVerifyReceiptFallsBackToSingleReceipts(t *testing.T) {
+	block, receipts := newTestBlock(t)
+
+	byHash := make(map[common.Hash]*types.Receipt)
+	for _, receipt := range receipts {
+		byHash[receipt.TxHash] = receipt
+	}
+
+	client := &fakeClient{block: block, blockReceiptsErr: errors.New("method not found"), receipts: byHash}
+	v := NewVerifier(client)
+
+	for _, tx := range block.Transactions() {
+		receipt, err := v.VerifyReceipt(context.Background(), block.Hash(), tx.Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if receipt.TxHash != tx.Hash() {
+			t.Fatalf("verified receipt of tx (%s), want (%s)", receipt.TxHash.Hex(), tx.Hash().Hex())
+		}
+	}
+
+	// The verified receipts of the block are cached after the first transaction
+	if client.blockReceiptCalls != 1 || client.receiptCalls != len(receipts) {
+		t.Fatalf("%d block receipts and %d receipt requests, want 1 and %d", client.blockReceiptCalls, client.receiptCalls, len(receipts))
+	}
+}
+
+// newTestBlock returns a block of three transactions with their receipts.
+func newTestBlock(t *testing.T) (*types.Block, types.Receipts) {
+	t.Helper()
+
+	transactions := types.Transactions{}
+	receipts := types.Receipts{}
+	for i := uint64(0); i < 3; i++ {
+		tx := newTestTx(t, i)
+		transactions = append(transactions, tx)
+		receipts = append(receipts, &types.Receipt{
+			Type:              tx.Type(),
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: 21000 * (i + 1),
+			TxHash:            tx.Hash(),
+			GasUsed:           21000,
+			Logs:              []*types.Log{},
+		})
+	}
+
+	header := &types.Header{Number: big.NewInt(7), Difficulty: big.NewInt(0)}
+	return types.NewBlock(header, &types.Body{Transactions: transactions}, receipts, trie.NewStackTrie(nil)), receipts
+}
+
+func newTestTx(t *testing.T, nonce uint64) *types.Transaction {
+	t.Helper()
+
+	key, err := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(1)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(10),
+		Gas:       21000,
+		To:        &common.Address{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+type fakeClient struct {
+	block             *types.Block
+	blockReceipts     types.Receipts
+	blockReceiptsErr  error
+	receipts          map[common.Hash]*types.Receipt
+	blockReceiptCalls int
+	receiptCalls      int
+
+	mu sync.Mutex
+}
+
+func (c *fakeClient) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return c.block, nil
+}
+
+func (c *fakeClient) BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	c.blockReceiptCalls++
+	return c.blockReceipts, c.blockReceiptsErr
+}
+
+// TransactionReceipt is called concurrently by the fallback.
+func (c *fakeClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.receiptCalls++
+	receipt, ok := c.receipts[txHash]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return receipt, nil
+}
@@ -0,0 +1,357 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+)
+
+func NewDispatcher(storage storage, httpClient *http.Client) *dispatcher {
+	return &dispatcher{
+		storage:        storage,
+		httpClient:     httpClient,
+		addressAllowed: isPublic,
+	}
+}
+
+// NewHttpClient returns the client to send deliveries with. It refuses to connect to loopback, private
+// and link-local addresses, whatever the webhook host resolves to by the time of the delivery.
+func NewHttpClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: dialTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+				return fmt.Errorf("webhook address (%s) is not public", host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// A proxy would connect to the webhook on our behalf, past the check
+	transport.Proxy = nil
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// Subscribe registers a webhook and returns it together with its signing secret.
+// The secret is only handed out once.
+func (d *dispatcher) Subscribe(ctx context.Context, username, webhookUrl string, eventTypes []model.EventType) (model.WebhookSubscription, string, error) {
+	parsedUrl, err := url.Parse(webhookUrl)
+	if err != nil || (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") || parsedUrl.Host == "" {
+		return model.WebhookSubscription{}, "", fmt.Errorf("invalid webhook url (%s)", webhookUrl)
+	}
+
+	if err := d.checkHost(ctx, parsedUrl.Hostname()); err != nil {
+		return model.WebhookSubscription{}, "", err
+	}
+
+	if len(eventTypes) == 0 {
+		eventTypes = allEventTypes
+	}
+
+	types := []string{}
+	for _, eventType := range eventTypes {
+		if !isKnownEventType(eventType) {
+			return model.WebhookSubscription{}, "", fmt.Errorf("unknown event type (%s)", eventType)
+		}
+		types = append(types, string(eventType))
+	}
+
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return model.WebhookSubscription{}, "", err
+	}
+	secret := hex.EncodeToString(secretBytes)
+
+	subscription, err := d.storage.CreateWebhook(ctx, model.WebhookSubscription{
+		Username:   username,
+		Url:        webhookUrl,
+		Secret:     secret,
+		EventTypes: strings.Join(types, ","),
+	})
+	if err != nil {
+		return model.WebhookSubscription{}, "", err
+	}
+
+	return subscription, secret, nil
+}
+
+// checkHost rejects hosts with a non-public address. Deliveries are checked again when connecting,
+// as the host may resolve differently by then.
+func (d *dispatcher) checkHost(ctx context.Context, host string) error {
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve webhook host (%s): %s", host, err)
+	}
+
+	for _, address := range addresses {
+		if !d.addressAllowed(address.IP) {
+			return fmt.Errorf("webhook host (%s) resolves to a non-public address (%s)", host, address.IP)
+		}
+	}
+
+	return nil
+}
+
+func (d *dispatcher) Unsubscribe(ctx context.Context, username string, id int64) error {
+	deleted, err := d.storage.DeleteWebhook(ctx, username, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("webhook (%d) not found", id)
+	}
+	return nil
+}
+
+func (d *dispatcher) Subscriptions(ctx context.Context, username string) ([]model.WebhookSubscription, error) {
+	return d.storage.GetWebhooks(ctx, username)
+}
+
+func (d *dispatcher) Deliveries(ctx context.Context, username string, subscriptionID int64, limit int) ([]model.WebhookDelivery, error) {
+	return d.storage.GetDeliveries(ctx, username, subscriptionID, limit)
+}
+
+// Enqueue writes a delivery to the outbox for every subscription of the event's user
+// that asked for the event type.
+func (d *dispatcher) Enqueue(ctx context.Context, events <-chan model.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := d.enqueue(ctx, event); err != nil {
+				log.Println(fmt.Errorf("failed to enqueue %s webhooks of (%s): %s", event.Type, event.Username, err))
+			}
+		}
+	}
+}
+
+func (d *dispatcher) enqueue(ctx context.Context, event model.Event) error {
+	if event.Username == "" {
+		return nil
+	}
+
+	subscriptions, err := d.storage.GetWebhooks(ctx, event.Username)
+	if err != nil {
+		return err
+	}
+
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UnixNano()
+	for _, subscription := range subscriptions {
+		if !subscribesTo(subscription, event.Type) {
+			continue
+		}
+
+		if err := d.storage.EnqueueDelivery(ctx, model.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventType:      event.Type,
+			Payload:        string(payload),
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Deliver sends the due deliveries of the outbox until the context is done.
+func (d *dispatcher) Deliver(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deliveries, subscriptions, err := d.storage.ClaimDueDeliveries(ctx, deliveryBatch, deliveryLease)
+			if err != nil {
+				log.Println(fmt.Errorf("failed to claim webhook deliveries: %s", err))
+				continue
+			}
+
+			for i := range deliveries {
+				d.deliver(ctx, deliveries[i], subscriptions[i])
+			}
+		}
+	}
+}
+
+func (d *dispatcher) deliver(ctx context.Context, delivery model.WebhookDelivery, subscription model.WebhookSubscription) {
+	started := time.Now()
+	statusCode, err := d.post(ctx, delivery, subscription)
+
+	delivery.Attempts++
+	attempt := model.WebhookDeliveryAttempt{
+		Attempt:    delivery.Attempts,
+		DurationMs: time.Since(started).Milliseconds(),
+		CreatedAt:  time.Now().UnixNano(),
+	}
+
+	if statusCode != 0 {
+		attempt.StatusCode = &statusCode
+	}
+
+	if err == nil {
+		deliveredAt := time.Now().UnixNano()
+		delivery.Status = model.DeliveryDelivered
+		delivery.DeliveredAt = &deliveredAt
+		delivery.LastError = nil
+	} else {
+		message := err.Error()
+		attempt.Error = &message
+		delivery.LastError = &message
+
+		if delivery.Attempts >= maxAttempts {
+			delivery.Status = model.DeliveryFailed
+		} else {
+			delivery.NextAttemptAt = time.Now().Add(backoff(delivery.Attempts)).UnixNano()
+		}
+	}
+
+	if err := d.storage.RecordDeliveryAttempt(ctx, delivery, attempt); err != nil {
+		log.Println(fmt.Errorf("failed to record attempt of webhook delivery (%d): %s", delivery.ID, err))
+	}
+}
+
+func (d *dispatcher) post(ctx context.Context, delivery model.WebhookDelivery, subscription model.WebhookSubscription) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Url, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Lime-Event", string(delivery.EventType))
+	request.Header.Set("X-Lime-Delivery", strconv.FormatInt(delivery.ID, 10))
+	request.Header.Set("X-Lime-Signature", fmt.Sprintf("t=%s,v1=%s", timestamp, Sign(subscription.Secret, timestamp, delivery.Payload)))
+
+	response, err := d.httpClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(response.Body, 4096))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+
+	return response.StatusCode, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of "<timestamp>.<payload>". Receivers recompute it
+// with their secret and compare it with the v1 value of the X-Lime-Signature header.
+func Sign(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func isPublic(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !sharedAddressSpace.Contains(ip)
+}
+
+func backoff(attempts int) time.Duration {
+	delay := initialBackoff << (attempts - 1)
+	if delay > maxBackoff || delay <= 0 {
+		return maxBackoff
+	}
+	return delay
+}
+
+func subscribesTo(subscription model.WebhookSubscription, eventType model.EventType) bool {
+	for _, subscribed := range strings.Split(subscription.EventTypes, ",") {
+		if model.EventType(subscribed) == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func isKnownEventType(eventType model.EventType) bool {
+	for _, known := range allEventTypes {
+		if known == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+var allEventTypes = []model.EventType{
+	model.TxConfirmedEvent,
+	model.TxFailedEvent,
+	model.TxDroppedEvent,
+	model.TxFinalizedEvent,
+	model.TxReorgedEvent,
+	model.TxReplacedEvent,
+	model.WatchMatchEvent,
+}
+
+// 100.64.0.0/10, used for carrier-grade NAT
+var sharedAddressSpace = &net.IPNet{IP: net.IP{100, 64, 0, 0}, Mask: net.CIDRMask(10, 32)}
+
+const (
+	dialTimeout    = 10 * time.Second
+	deliveryBatch  = 50
+	deliveryLease  = time.Minute
+	maxAttempts    = 8
+	initialBackoff = 10 * time.Second
+	maxBackoff     = time.Hour
+)
+
+type storage interface {
+	CreateWebhook(ctx context.Context, subscription model.WebhookSubscription) (model.WebhookSubscription, error)
+	DeleteWebhook(ctx context.Context, username string, id int64) (bool, error)
+	GetWebhooks(ctx context.Context, username string) ([]model.WebhookSubscription, error)
+	EnqueueDelivery(ctx context.Context, delivery model.WebhookDelivery) error
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]model.WebhookDelivery, []model.WebhookSubscription, error)
+	RecordDeliveryAttempt(ctx context.Context, delivery model.WebhookDelivery, attempt model.WebhookDeliveryAttempt) error
+	GetDeliveries(ctx context.Context, username string, subscriptionID int64, limit int) ([]model.WebhookDelivery, error)
+}
+
+type dispatcher struct {
+	storage        storage
+	httpClient     *http.Client
+	addressAllowed func(ip net.IP) bool
+}
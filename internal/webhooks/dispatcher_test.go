@@ -0,0 +1,315 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/avalkov/eth-node-interaction/internal/model"
+)
+
+func TestDeliverSignsPayload(t *testing.T) {
+	const secret = "s3cret"
+	payload := `{"type":"tx_confirmed"}`
+
+	var received *http.Request
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received, body = r, string(data)
+	}))
+	defer server.Close()
+
+	storage := &fakeStorage{}
+	d := newTestDispatcher(storage, server.Client())
+	d.deliver(context.Background(), model.WebhookDelivery{ID: 7, EventType: model.TxConfirmedEvent, Payload: payload},
+		model.WebhookSubscription{Url: server.URL, Secret: secret})
+
+	if received == nil {
+		t.Fatal("webhook was not called")
+	}
+	if body != payload {
+		t.Fatalf("body = %s, want %s", body, payload)
+	}
+	if got := received.Header.Get("X-Lime-Event"); got != string(model.TxConfirmedEvent) {
+		t.Fatalf("X-Lime-Event = %s", got)
+	}
+	if got := received.Header.Get("X-Lime-Delivery"); got != "7" {
+		t.Fatalf("X-Lime-Delivery = %s", got)
+	}
+
+	timestamp, signature := parseSignature(t, received.Header.Get("X-Lime-Signature"))
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, payload))) {
+		t.Fatalf("signature %s does not verify", signature)
+	}
+	if hmac.Equal([]byte(signature), []byte(Sign("other", timestamp, payload))) {
+		t.Fatal("signature verifies with another secret")
+	}
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{}' | openssl dgst -sha256 -hmac secret
+	const want = "b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163"
+	if got := Sign("secret", "1700000000", "{}"); got != want {
+		t.Fatalf("Sign = %s, want %s", got, want)
+	}
+}
+
+func TestDeliverRetriesWithBackoff(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	storage := &fakeStorage{}
+	d := newTestDispatcher(storage, server.Client())
+	subscription := model.WebhookSubscription{Url: server.URL, Secret: "secret"}
+
+	started := time.Now()
+	d.deliver(context.Background(), model.WebhookDelivery{ID: 1, Payload: "{}"}, subscription)
+
+	failed := storage.deliveries[0]
+	if failed.Status != model.DeliveryPending {
+		t.Fatalf("status after a failed attempt = %d, want pending", failed.Status)
+	}
+	if failed.Attempts != 1 {
+		t.Fatalf("attempts = %d, want 1", failed.Attempts)
+	}
+	retryIn := time.Duration(failed.NextAttemptAt - started.UnixNano())
+	if retryIn < initialBackoff || retryIn > initialBackoff+time.Minute {
+		t.Fatalf("retry in %s, want about %s", retryIn, initialBackoff)
+	}
+	if failed.LastError == nil || !strings.Contains(*failed.LastError, "500") {
+		t.Fatalf("last error = %v", failed.LastError)
+	}
+
+	d.deliver(context.Background(), failed, subscription)
+
+	delivered := storage.deliveries[1]
+	if delivered.Status != model.DeliveryDelivered || delivered.DeliveredAt == nil || delivered.LastError != nil {
+		t.Fatalf("delivery after the retry = %+v", delivered)
+	}
+	if delivered.Attempts != 2 {
+		t.Fatalf("attempts = %d, want 2", delivered.Attempts)
+	}
+}
+
+func TestDeliverGivesUpAfterMaxAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	storage := &fakeStorage{}
+	d := newTestDispatcher(storage, server.Client())
+	d.deliver(context.Background(), model.WebhookDelivery{ID: 1, Payload: "{}", Attempts: maxAttempts - 1},
+		model.WebhookSubscription{Url: server.URL})
+
+	if got := storage.deliveries[0].Status; got != model.DeliveryFailed {
+		t.Fatalf("status = %d, want failed", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, initialBackoff},
+		{2, 2 * initialBackoff},
+		{3, 4 * initialBackoff},
+		{12, maxBackoff},
+		{100, maxBackoff},
+	}
+
+	for _, test := range tests {
+		if got := backoff(test.attempts); got != test.want {
+			t.Errorf("backoff(%d) = %s, want %s", test.attempts, got, test.want)
+		}
+	}
+}
+
+func TestDeliverRecordsAttempts(t *testing.T) {
+	statuses := []int{http.StatusServiceUnavailable, http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[0])
+		statuses = statuses[1:]
+	}))
+	defer server.Close()
+
+	storage := &fakeStorage{}
+	d := newTestDispatcher(storage, server.Client())
+	subscription := model.WebhookSubscription{Url: server.URL}
+
+	d.deliver(context.Background(), model.WebhookDelivery{ID: 3, Payload: "{}"}, subscription)
+	d.deliver(context.Background(), storage.deliveries[0], subscription)
+
+	if len(storage.attempts) != 2 {
+		t.Fatalf("recorded %d attempts, want 2", len(storage.attempts))
+	}
+
+	first, second := storage.attempts[0], storage.attempts[1]
+	if first.Attempt != 1 || first.StatusCode == nil || *first.StatusCode != http.StatusServiceUnavailable || first.Error == nil {
+		t.Fatalf("first attempt = %+v", first)
+	}
+	if second.Attempt != 2 || second.StatusCode == nil || *second.StatusCode != http.StatusOK || second.Error != nil {
+		t.Fatalf("second attempt = %+v", second)
+	}
+	if first.CreatedAt == 0 || second.CreatedAt < first.CreatedAt {
+		t.Fatalf("attempt times = %d, %d", first.CreatedAt, second.CreatedAt)
+	}
+}
+
+func TestDeliverRecordsConnectionErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	storage := &fakeStorage{}
+	d := newTestDispatcher(storage, http.DefaultClient)
+	d.deliver(context.Background(), model.WebhookDelivery{ID: 4, Payload: "{}"}, model.WebhookSubscription{Url: url})
+
+	attempt := storage.attempts[0]
+	if attempt.StatusCode != nil || attempt.Error == nil {
+		t.Fatalf("attempt = %+v", attempt)
+	}
+}
+
+func TestSubscribeRejectsNonPublicHosts(t *testing.T) {
+	urls := []string{
+		"http://localhost:8080/hook",
+		"http://127.0.0.1/hook",
+		"http://[::1]/hook",
+		"http://10.1.2.3/hook",
+		"http://172.16.0.1/hook",
+		"http://192.168.1.10/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[fe80::1]/hook",
+		"http://100.64.0.1/hook",
+		"http://0.0.0.0/hook",
+	}
+
+	for _, url := range urls {
+		storage := &fakeStorage{}
+		d := NewDispatcher(storage, http.DefaultClient)
+		if _, _, err := d.Subscribe(context.Background(), "user", url, nil); err == nil {
+			t.Errorf("Subscribe(%s) succeeded", url)
+		}
+		if len(storage.created) != 0 {
+			t.Errorf("Subscribe(%s) stored the webhook", url)
+		}
+	}
+}
+
+func TestSubscribeAcceptsPublicHosts(t *testing.T) {
+	storage := &fakeStorage{}
+	d := NewDispatcher(storage, http.DefaultClient)
+
+	subscription, secret, err := d.Subscribe(context.Background(), "user", "https://93.184.216.34/hook", []model.EventType{model.TxConfirmedEvent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == "" || subscription.Secret != secret {
+		t.Fatalf("secret = %q, stored %q", secret, subscription.Secret)
+	}
+	if subscription.EventTypes != string(model.TxConfirmedEvent) {
+		t.Fatalf("event types = %s", subscription.EventTypes)
+	}
+}
+
+func TestHttpClientRefusesNonPublicAddresses(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	_, err := NewHttpClient(time.Second).Get(server.URL)
+	if err == nil || !strings.Contains(err.Error(), "not public") {
+		t.Fatalf("error = %v, want a refused connection", err)
+	}
+	if called {
+		t.Fatal("webhook on a loopback address was called")
+	}
+}
+
+// The test servers listen on loopback addresses
+func newTestDispatcher(storage storage, httpClient *http.Client) *dispatcher {
+	d := NewDispatcher(storage, httpClient)
+	d.addressAllowed = func(ip net.IP) bool { return true }
+	return d
+}
+
+func parseSignature(t *testing.T, header string) (string, string) {
+	t.Helper()
+
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		if value, ok := strings.CutPrefix(part, "t="); ok {
+			timestamp = value
+		}
+		if value, ok := strings.CutPrefix(part, "v1="); ok {
+			signature = value
+		}
+	}
+
+	if timestamp == "" || signature == "" {
+		t.Fatalf("malformed signature header (%s)", header)
+	}
+
+	return timestamp, signature
+}
+
+type fakeStorage struct {
+	mu         sync.Mutex
+	created    []model.WebhookSubscription
+	deliveries []model.WebhookDelivery
+	attempts   []model.WebhookDeliveryAttempt
+}
+
+func (s *fakeStorage) CreateWebhook(ctx context.Context, subscription model.WebhookSubscription) (model.WebhookSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subscription.ID = int64(len(s.created) + 1)
+	s.created = append(s.created, subscription)
+	return subscription, nil
+}
+
+func (s *fakeStorage) DeleteWebhook(ctx context.Context, username string, id int64) (bool, error) {
+	return false, nil
+}
+
+func (s *fakeStorage) GetWebhooks(ctx context.Context, username string) ([]model.WebhookSubscription, error) {
+	return s.created, nil
+}
+
+func (s *fakeStorage) EnqueueDelivery(ctx context.Context, delivery model.WebhookDelivery) error {
+	return nil
+}
+
+func (s *fakeStorage) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]model.WebhookDelivery, []model.WebhookSubscription, error) {
+	return nil, nil, nil
+}
+
+func (s *fakeStorage) RecordDeliveryAttempt(ctx context.Context, delivery model.WebhookDelivery, attempt model.WebhookDeliveryAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, delivery)
+	s.attempts = append(s.attempts, attempt)
+	return nil
+}
+
+func (s *fakeStorage) GetDeliveries(ctx context.Context, username string, subscriptionID int64, limit int) ([]model.WebhookDelivery, error) {
+	return nil, nil
+}